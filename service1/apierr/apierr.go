@@ -0,0 +1,278 @@
+// Package apierr describes errors returned by http api. Every error has a
+// machine readable code and http status and is rendered as RFC 7807 problem
+// details.
+package apierr
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"service1/database"
+	"strings"
+
+	validationv3 "github.com/go-ozzo/ozzo-validation"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// ContentType of problem details.
+const ContentType = "application/problem+json"
+
+// Code is machine readable error code.
+type Code string
+
+// Error codes.
+const (
+	CodeMalformed     Code = "malformed_request"
+	CodeValidation    Code = "validation_failed"
+	CodeBadArgument   Code = "bad_argument"
+	CodeUnauthorized  Code = "unauthorized"
+	CodeQuotaExceeded Code = "quota_exceeded"
+	CodeNotFound      Code = "not_found"
+	CodeNotInteger    Code = "not_integer"
+	CodeOutOfBounds   Code = "out_of_bounds"
+	CodeConflict      Code = "conflict"
+	CodeRemote        Code = "remote_failed"
+	CodeUnavailable   Code = "unavailable"
+	CodeInternal      Code = "internal"
+)
+
+var statuses = map[Code]int{
+	CodeMalformed:     http.StatusBadRequest,
+	CodeValidation:    http.StatusBadRequest,
+	CodeBadArgument:   http.StatusBadRequest,
+	CodeUnauthorized:  http.StatusUnauthorized,
+	CodeQuotaExceeded: http.StatusForbidden,
+	CodeNotFound:      http.StatusNotFound,
+	CodeNotInteger:    http.StatusConflict,
+	CodeOutOfBounds:   http.StatusConflict,
+	CodeConflict:      http.StatusConflict,
+	CodeRemote:        http.StatusBadGateway,
+	CodeUnavailable:   http.StatusServiceUnavailable,
+	CodeInternal:      http.StatusInternalServerError,
+}
+
+// Status returns http status of code.
+func (c Code) Status() int {
+	if s, ok := statuses[c]; ok {
+		return s
+	}
+
+	return http.StatusInternalServerError
+}
+
+// Error .
+type Error struct {
+	Code Code
+	// Detail is shown to client, internal errors have none.
+	Detail string
+	// Fields holds validation errors by field name or array index, nested
+	// the same way as ozzo validation.Errors.
+	Fields map[string]interface{}
+	// Ext are additional members of problem details.
+	Ext map[string]interface{}
+	// Err is the cause.
+	Err error
+}
+
+// New .
+func New(code Code, err error) *Error {
+	e := &Error{Code: code, Err: err}
+	if code != CodeInternal {
+		e.Detail = err.Error()
+	}
+
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status .
+func (e *Error) Status() int {
+	return e.Code.Status()
+}
+
+// Malformed is error of request that can't be decoded. Values of wrong json
+// type are reported in Fields like validation errors.
+func Malformed(err error) *Error {
+	e := New(CodeMalformed, err)
+
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		e.Detail = "request body is empty"
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		e.Detail = "request has fields of wrong type"
+		e.Fields = nestField(strings.Split(strings.Trim(typeErr.Field, "."), "."), "must be "+jsonType(typeErr.Type))
+	}
+
+	return e
+}
+
+// nestField puts msg to path like "0.a" of decoding error.
+func nestField(path []string, msg string) map[string]interface{} {
+	if len(path) == 1 {
+		return map[string]interface{}{path[0]: msg}
+	}
+
+	return map[string]interface{}{path[0]: nestField(path[1:], msg)}
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Ptr:
+		return jsonType(t.Elem())
+	}
+
+	return "an object"
+}
+
+// Invalid is error of request that didn't pass validation. Field errors of
+// ozzo validation.Errors are kept in Fields.
+func Invalid(err error) *Error {
+	e := New(CodeValidation, err)
+	if fields, ok := fieldErrors(err); ok {
+		e.Detail = "request has invalid fields"
+		e.Fields = fields
+	}
+
+	return e
+}
+
+// Remote is error of call to remote server, its details are not shown.
+func Remote(err error) *Error {
+	return &Error{Code: CodeRemote, Detail: "remote server failed", Err: err}
+}
+
+// From maps err to api error. Already mapped errors are returned as is,
+// unknown ones become internal.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	var bound *database.BoundError
+	if errors.As(err, &bound) {
+		e := New(CodeOutOfBounds, err)
+		e.Ext = map[string]interface{}{"current": bound.Val}
+		return e
+	}
+
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return New(CodeNotFound, err)
+	case errors.Is(err, database.ErrBadStep), errors.Is(err, database.ErrCrossSlot):
+		return New(CodeBadArgument, err)
+	case errors.Is(err, database.ErrNotInteger):
+		return New(CodeNotInteger, err)
+	case errors.Is(err, database.ErrTxConflict):
+		return New(CodeConflict, err)
+	case errors.Is(err, database.ErrQuotaExceeded):
+		return New(CodeQuotaExceeded, err)
+	case errors.Is(err, database.ErrUnavailable):
+		return New(CodeUnavailable, err)
+	}
+
+	return New(CodeInternal, err)
+}
+
+// fieldErrors converts ozzo validation.Errors of both v3 and v4 to nested
+// map of messages.
+func fieldErrors(err error) (map[string]interface{}, bool) {
+	var errs map[string]error
+	switch e := err.(type) {
+	case validation.Errors:
+		errs = e
+	case validationv3.Errors:
+		errs = e
+	default:
+		return nil, false
+	}
+
+	fields := make(map[string]interface{}, len(errs))
+	for k, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		if nested, ok := fieldErrors(err); ok {
+			fields[k] = nested
+			continue
+		}
+
+		fields[k] = err.Error()
+	}
+
+	return fields, true
+}
+
+// Problem is RFC 7807 problem details object.
+type Problem struct {
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Status    int                    `json:"status"`
+	Detail    string                 `json:"detail,omitempty"`
+	Instance  string                 `json:"instance,omitempty"`
+	Code      Code                   `json:"code"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Errors    map[string]interface{} `json:"errors,omitempty"`
+	// Ext members are written next to standard ones.
+	Ext map[string]interface{} `json:"-"`
+}
+
+// Problem builds problem details of e for request instance.
+func (e *Error) Problem(instance string) *Problem {
+	return &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(e.Status()),
+		Status:   e.Status(),
+		Detail:   e.Detail,
+		Instance: instance,
+		Code:     e.Code,
+		Errors:   e.Fields,
+		Ext:      e.Ext,
+	}
+}
+
+// MarshalJSON .
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	bs, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.Ext) == 0 {
+		return bs, err
+	}
+
+	m := make(map[string]interface{}, len(p.Ext))
+	for k, v := range p.Ext {
+		m[k] = v
+	}
+
+	// standard members win over extensions with the same name
+	if err := json.Unmarshal(bs, &m); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(m)
+}
@@ -0,0 +1,148 @@
+package apierr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"service1/database"
+	"strings"
+	"testing"
+
+	validationv3 "github.com/go-ozzo/ozzo-validation"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrom(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		code   Code
+		status int
+		detail string
+	}{
+		{
+			name:   "not found",
+			err:    fmt.Errorf("get: %w", database.ErrNotFound),
+			code:   CodeNotFound,
+			status: http.StatusNotFound,
+			detail: "get: counter not found",
+		},
+		{
+			name:   "cross slot",
+			err:    database.ErrCrossSlot,
+			code:   CodeBadArgument,
+			status: http.StatusBadRequest,
+			detail: database.ErrCrossSlot.Error(),
+		},
+		{
+			name:   "quota",
+			err:    database.ErrQuotaExceeded,
+			code:   CodeQuotaExceeded,
+			status: http.StatusForbidden,
+			detail: database.ErrQuotaExceeded.Error(),
+		},
+		{
+			name:   "unavailable",
+			err:    database.ErrUnavailable,
+			code:   CodeUnavailable,
+			status: http.StatusServiceUnavailable,
+			detail: database.ErrUnavailable.Error(),
+		},
+		{
+			name:   "already mapped",
+			err:    fmt.Errorf("wrapped: %w", New(CodeUnauthorized, errors.New("no key"))),
+			code:   CodeUnauthorized,
+			status: http.StatusUnauthorized,
+			detail: "no key",
+		},
+		{
+			name:   "internal hides detail",
+			err:    errors.New("secret dsn"),
+			code:   CodeInternal,
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := From(tc.err)
+			assert.Equal(t, tc.code, e.Code)
+			assert.Equal(t, tc.status, e.Status())
+			assert.Equal(t, tc.detail, e.Detail)
+		})
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		detail string
+		fields map[string]interface{}
+	}{
+		{
+			name:   "single value",
+			err:    validation.Validate("", validation.Required),
+			detail: "cannot be blank",
+		},
+		{
+			name:   "fields",
+			err:    validation.Errors{"key": validation.ErrRequired},
+			detail: "request has invalid fields",
+			fields: map[string]interface{}{"key": "cannot be blank"},
+		},
+		{
+			name: "array index of v3 with v4 fields",
+			err: validationv3.Errors{
+				"1": validation.Errors{"a": validation.ErrRequired},
+			},
+			detail: "request has invalid fields",
+			fields: map[string]interface{}{
+				"1": map[string]interface{}{"a": "cannot be blank"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := Invalid(tc.err)
+			assert.Equal(t, CodeValidation, e.Code)
+			assert.Equal(t, tc.detail, e.Detail)
+			assert.Equal(t, tc.fields, e.Fields)
+		})
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	var pairs []struct {
+		A string `json:"a"`
+	}
+	err := json.NewDecoder(strings.NewReader(`[{"a": "x"}, {"a": 1}]`)).Decode(&pairs)
+
+	e := Malformed(err)
+	assert.Equal(t, CodeMalformed, e.Code)
+	assert.Equal(t, map[string]interface{}{
+		"1": map[string]interface{}{"a": "must be a string"},
+	}, e.Fields)
+
+	err = json.NewDecoder(strings.NewReader(``)).Decode(&pairs)
+	assert.Equal(t, "request body is empty", Malformed(err).Detail)
+}
+
+func TestProblem_MarshalJSON(t *testing.T) {
+	e := From(&database.BoundError{Val: 3})
+
+	bs, err := json.Marshal(e.Problem("/counters/x"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Conflict",
+		"status": 409,
+		"detail": "counter bound would be violated",
+		"instance": "/counters/x",
+		"code": "out_of_bounds",
+		"current": 3
+	}`, string(bs))
+}
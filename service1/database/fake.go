@@ -3,12 +3,60 @@ package database
 import (
 	"context"
 	"errors"
+	"time"
 )
 
+var errFake = errors.New("some error ocured")
+
 // FakeDB .
 type FakeDB struct{}
 
 // IncrementBy .
-func (*FakeDB) IncrementBy(context.Context, string, int64) (int64, error) {
-	return 0, errors.New("some error ocured")
+func (*FakeDB) IncrementBy(context.Context, string, int64, IncrOpts) (*Counter, error) {
+	return nil, errFake
+}
+
+// IncrementMany .
+func (*FakeDB) IncrementMany(context.Context, map[string]int64) (map[string]int64, error) {
+	return nil, errFake
+}
+
+// Get .
+func (*FakeDB) Get(context.Context, string) (int64, error) {
+	return 0, errFake
+}
+
+// GetMany .
+func (*FakeDB) GetMany(context.Context, []string) (map[string]int64, error) {
+	return nil, errFake
+}
+
+// Set .
+func (*FakeDB) Set(context.Context, string, int64) error {
+	return errFake
+}
+
+// Delete .
+func (*FakeDB) Delete(context.Context, string) error {
+	return errFake
+}
+
+// Count .
+func (*FakeDB) Count(context.Context, string) (int64, error) {
+	return 0, errFake
+}
+
+// Subscribe .
+func (*FakeDB) Subscribe(context.Context, string) (<-chan *Event, error) {
+	return nil, errFake
+}
+
+// Events .
+func (*FakeDB) Events(context.Context, string, int64) ([]*Event, error) {
+	return nil, errFake
+}
+
+// History .
+func (*FakeDB) History(context.Context, string, time.Time, time.Time, time.Duration) ([]*Point, error) {
+	return nil, errFake
 }
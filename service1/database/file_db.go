@@ -0,0 +1,311 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Fsync policies of FileDB log.
+const (
+	// FsyncAlways syncs log before every operation returns.
+	FsyncAlways = "always"
+	// FsyncInterval syncs log once per second in background.
+	FsyncInterval = "interval"
+	// FsyncNever leaves syncing to operating system.
+	FsyncNever = "never"
+)
+
+const (
+	logFile      = "counters.log"
+	snapshotFile = "counters.snapshot"
+	fsyncPeriod  = time.Second
+)
+
+// ErrUnknownFsync .
+var ErrUnknownFsync = errors.New("unknown fsync policy")
+
+// logRecord is one line of the log. Changes of one operation share a record,
+// so a torn write after crash drops the whole operation.
+type logRecord struct {
+	Seq     int64     `json:"seq"`
+	Changes []*change `json:"changes"`
+}
+
+type fileSnapshot struct {
+	snapshot
+	// Seq is sequence of the last record included into snapshot.
+	Seq int64 `json:"seq"`
+}
+
+// FileDB is MemoryDB backed by append-only log in dir. Log is compacted into
+// a snapshot every compaction interval and on Stop.
+type FileDB struct {
+	*MemoryDB
+
+	dir   string
+	fsync string
+
+	// f, off and seq are guarded by MemoryDB lock as journal runs under it.
+	f   *os.File
+	off int64
+	seq int64
+
+	// fmu guards f replacement against background sync.
+	fmu sync.Mutex
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+var _ DB = (*FileDB)(nil)
+
+// NewFileDB restores state from dir, keeping history for retention, and opens
+// log for appending.
+func NewFileDB(dir, fsync string, compactInterval time.Duration, retention Retention) (*FileDB, error) {
+	if fsync != FsyncAlways && fsync != FsyncInterval && fsync != FsyncNever {
+		return nil, fmt.Errorf("%w %q", ErrUnknownFsync, fsync)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cant create data dir %w", err)
+	}
+
+	mem, err := NewMemoryDB("", 0)
+	if err != nil {
+		return nil, err
+	}
+	// history is rebuilt by replay, so retention is set before it
+	mem.Retention = retention
+
+	db := &FileDB{
+		MemoryDB: mem,
+		dir:      dir,
+		fsync:    fsync,
+		stop:     make(chan struct{}),
+	}
+
+	if err := db.restore(); err != nil {
+		return nil, err
+	}
+	db.trimHistory()
+
+	mem.journal = db.append
+
+	if fsync == FsyncInterval {
+		db.loop(fsyncPeriod, db.sync)
+	}
+
+	if compactInterval > 0 {
+		db.loop(compactInterval, db.Compact)
+	}
+
+	return db, nil
+}
+
+// Stop compacts log and closes it.
+func (db *FileDB) Stop() error {
+	select {
+	case <-db.stop:
+		return nil
+	default:
+		close(db.stop)
+	}
+
+	db.wg.Wait()
+
+	if err := db.Compact(); err != nil {
+		return err
+	}
+
+	return db.f.Close()
+}
+
+// Compact writes current state into snapshot and starts an empty log.
+func (db *FileDB) Compact() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	bs, err := json.Marshal(&fileSnapshot{
+		snapshot: snapshot{Counters: db.counters, History: db.history},
+		Seq:      db.seq,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(filepath.Join(db.dir, snapshotFile), bs); err != nil {
+		return fmt.Errorf("cant write snapshot %w", err)
+	}
+
+	// records up to seq are in snapshot now and skipped on restore, so crash
+	// before log is replaced is safe
+	f, err := createFileAtomic(filepath.Join(db.dir, logFile))
+	if err != nil {
+		return fmt.Errorf("cant replace log %w", err)
+	}
+
+	db.fmu.Lock()
+	old := db.f
+	db.f, db.off = f, 0
+	db.fmu.Unlock()
+
+	return old.Close()
+}
+
+// append writes changes as one log record, partial write is truncated so log
+// stays readable. Called by MemoryDB under its lock.
+func (db *FileDB) append(changes []*change) error {
+	bs, err := json.Marshal(&logRecord{Seq: db.seq + 1, Changes: changes})
+	if err != nil {
+		return err
+	}
+	bs = append(bs, '\n')
+
+	n, err := db.f.Write(bs)
+	if err == nil && db.fsync == FsyncAlways {
+		err = db.f.Sync()
+	}
+
+	if err != nil {
+		if n > 0 {
+			if terr := db.f.Truncate(db.off); terr != nil {
+				return fmt.Errorf("cant write log %v, cant truncate it %w", err, terr)
+			}
+		}
+		return fmt.Errorf("cant write log %w", err)
+	}
+
+	db.seq++
+	db.off += int64(n)
+	return nil
+}
+
+func (db *FileDB) sync() error {
+	db.fmu.Lock()
+	defer db.fmu.Unlock()
+
+	return db.f.Sync()
+}
+
+// loop calls fn every interval until Stop.
+func (db *FileDB) loop(interval time.Duration, fn func() error) {
+	db.wg.Add(1)
+
+	go func() {
+		defer db.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-db.stop:
+				return
+			case <-ticker.C:
+				if err := fn(); err != nil {
+					slog.Error("background task failed", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// restore loads snapshot and replays log records newer than it. Torn record
+// at the end of log is a result of crash during write and is cut off.
+func (db *FileDB) restore() error {
+	bs, err := os.ReadFile(filepath.Join(db.dir, snapshotFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cant read snapshot %w", err)
+	}
+
+	if err == nil {
+		var snap fileSnapshot
+		if err := json.Unmarshal(bs, &snap); err != nil {
+			return fmt.Errorf("cant parse snapshot %w", err)
+		}
+
+		if snap.Counters != nil {
+			db.counters = snap.Counters
+		}
+		if snap.History != nil {
+			db.history = snap.History
+		}
+		db.seq = snap.Seq
+	}
+
+	f, err := os.OpenFile(filepath.Join(db.dir, logFile), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("cant open log %w", err)
+	}
+
+	off, err := db.replay(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Truncate(off); err != nil {
+		f.Close()
+		return fmt.Errorf("cant truncate log %w", err)
+	}
+
+	db.f, db.off = f, off
+	return nil
+}
+
+// replay applies log records and returns offset after the last complete one.
+func (db *FileDB) replay(r io.Reader) (int64, error) {
+	reader := bufio.NewReader(r)
+	var off int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				slog.Warn("dropping torn log record", "offset", off)
+			}
+			return off, nil
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("cant read log %w", err)
+		}
+
+		var rec logRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return 0, fmt.Errorf("corrupted log record at offset %v %w", off, err)
+		}
+
+		if rec.Seq > db.seq {
+			for _, c := range rec.Changes {
+				db.apply(c)
+			}
+			db.seq = rec.Seq
+		}
+
+		off += int64(len(line))
+	}
+}
+
+// createFileAtomic replaces path with an empty file.
+func createFileAtomic(path string) (*os.File, error) {
+	if err := writeFileAtomic(path, nil); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
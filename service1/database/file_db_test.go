@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileDB_Restore(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	db, err := NewFileDB(dir, FsyncAlways, 0, DefaultRetention)
+	assert.NoError(t, err)
+
+	_, err = db.IncrementBy(ctx, "x", 3, IncrOpts{TTL: time.Hour})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Set(ctx, "y", 7))
+	assert.NoError(t, db.Delete(ctx, "y"))
+	_, err = db.IncrementMany(ctx, map[string]int64{"x": 1, "z": 2})
+	assert.NoError(t, err)
+
+	// crash: log is left as is without compaction
+	assert.NoError(t, db.f.Close())
+
+	db, err = NewFileDB(dir, FsyncAlways, 0, DefaultRetention)
+	assert.NoError(t, err)
+
+	m, err := db.GetMany(ctx, []string{"x", "y", "z"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"x": 4, "z": 2}, m)
+
+	c, err := db.IncrementBy(ctx, "x", 1, IncrOpts{})
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour, c.TTL, float64(time.Second))
+
+	assert.NoError(t, db.Stop())
+
+	info, err := os.Stat(filepath.Join(dir, logFile))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+
+	db, err = NewFileDB(dir, FsyncNever, 0, DefaultRetention)
+	assert.NoError(t, err)
+	defer db.Stop()
+
+	val, err := db.Get(ctx, "x")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), val)
+}
+
+func TestFileDB_TornRecord(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	db, err := NewFileDB(dir, FsyncInterval, 0, DefaultRetention)
+	assert.NoError(t, err)
+
+	_, err = db.IncrementBy(ctx, "x", 3, IncrOpts{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.f.Close())
+
+	f, err := os.OpenFile(filepath.Join(dir, logFile), os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"seq":2,"changes":[{"op":"incr","key":"x","val":`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	db, err = NewFileDB(dir, FsyncAlways, 0, DefaultRetention)
+	assert.NoError(t, err)
+	defer db.Stop()
+
+	val, err := db.Get(ctx, "x")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), val)
+
+	_, err = db.IncrementBy(ctx, "x", 1, IncrOpts{})
+	assert.NoError(t, err)
+
+	bs, err := os.ReadFile(filepath.Join(dir, logFile))
+	assert.NoError(t, err)
+	assert.Contains(t, string(bs), `{"seq":2,"changes":[{"op":"incr","key":"x","val":4,"delta":1,`)
+}
+
+func TestFileDB_RestoreRetention(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	db, err := NewFileDB(dir, FsyncAlways, 0, DefaultRetention)
+	assert.NoError(t, err)
+
+	// one increment is compacted into snapshot, other one stays in log
+	_, err = db.IncrementBy(ctx, "x", 3, IncrOpts{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Compact())
+	_, err = db.IncrementBy(ctx, "x", 2, IncrOpts{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.f.Close())
+
+	db, err = NewFileDB(dir, FsyncAlways, 0, Retention{Minute: time.Hour})
+	assert.NoError(t, err)
+	defer db.Stop()
+
+	now := time.Now()
+	assert.Len(t, db.history["x"].Minute, 1)
+	assert.Empty(t, db.history["x"].Hour)
+
+	points, err := db.History(ctx, "x", now.Add(-time.Hour), now.Add(time.Hour), time.Hour)
+	assert.NoError(t, err)
+
+	var total int64
+	for _, p := range points {
+		total += p.Delta
+	}
+	assert.Equal(t, int64(5), total)
+}
+
+func TestFileDB_UnknownFsync(t *testing.T) {
+	_, err := NewFileDB(t.TempDir(), "sometimes", 0, DefaultRetention)
+	assert.ErrorIs(t, err, ErrUnknownFsync)
+}
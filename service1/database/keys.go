@@ -0,0 +1,46 @@
+package database
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrCrossSlot .
+var ErrCrossSlot = errors.New("keys of atomic operation must share a hash tag like {tag} in cluster mode")
+
+// hashTag returns part of key redis cluster hashes to choose a slot, it is
+// content of the first non-empty {...} or the whole key.
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+
+	return key[start+1 : start+1+end]
+}
+
+// sameSlot reports whether keys are guaranteed to be in the same cluster slot.
+func sameSlot(keys []string) bool {
+	if len(keys) == 0 {
+		return true
+	}
+
+	for _, k := range keys[1:] {
+		if hashTag(k) != hashTag(keys[0]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// auxKey names helper key of a counter. It carries hash tag of the counter,
+// so scripts touching both stay within one cluster slot.
+func auxKey(kind, key string) string {
+	return kind + ":{" + hashTag(key) + "}:" + key
+}
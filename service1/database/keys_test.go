@@ -0,0 +1,28 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSameSlot(t *testing.T) {
+	testCases := []struct {
+		name string
+		keys []string
+		res  bool
+	}{
+		{name: "one key", keys: []string{"x"}, res: true},
+		{name: "shared tag", keys: []string{"{user1}:a", "b:{user1}"}, res: true},
+		{name: "different tags", keys: []string{"{user1}:a", "{user2}:a"}, res: false},
+		{name: "no tags", keys: []string{"a", "b"}, res: false},
+		{name: "empty tag is not a tag", keys: []string{"{}a", "{}b"}, res: false},
+		{name: "tenant prefix", keys: []string{"t:{acme}:a", "t:{acme}:b"}, res: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.res, sameSlot(tc.keys))
+		})
+	}
+}
@@ -0,0 +1,575 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// subBuffer is number of events kept for slow subscriber before dropping.
+const subBuffer = 64
+
+type entry struct {
+	Val int64 `json:"val"`
+	// Exp is expiration time in unix nanoseconds, zero if never expires.
+	Exp int64 `json:"exp,omitempty"`
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return e.Exp != 0 && e.Exp <= now.UnixNano()
+}
+
+func (e *entry) ttl(now time.Time) time.Duration {
+	if e.Exp == 0 {
+		return -1
+	}
+
+	return time.Duration(e.Exp - now.UnixNano())
+}
+
+// change is a single counter mutation. All writes of MemoryDB are applied as
+// changes, FileDB also stores them as log records.
+type change struct {
+	Op    string `json:"op"`
+	Key   string `json:"key"`
+	Val   int64  `json:"val,omitempty"`
+	Delta int64  `json:"delta,omitempty"`
+	Exp   int64  `json:"exp,omitempty"`
+	// Time is unix nanoseconds of the change.
+	Time int64 `json:"ts"`
+}
+
+type keyHistory struct {
+	Minute map[int64]int64 `json:"m,omitempty"`
+	Hour   map[int64]int64 `json:"h,omitempty"`
+}
+
+type snapshot struct {
+	Counters map[string]*entry      `json:"counters"`
+	History  map[string]*keyHistory `json:"history,omitempty"`
+}
+
+// MemoryDB keeps counters in process memory. With snapshot path it saves
+// state periodically and on Stop, and loads it back on start.
+type MemoryDB struct {
+	// Retention of increments history, zero disables resolution.
+	Retention Retention
+
+	mu       sync.Mutex
+	counters map[string]*entry
+	history  map[string]*keyHistory
+	events   map[string][]*Event
+	subs     map[string]map[chan *Event]struct{}
+	lastID   int64
+
+	// journal is called under lock before changes are applied, failed
+	// journal aborts the operation.
+	journal func([]*change) error
+
+	path string
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ DB = (*MemoryDB)(nil)
+
+// NewMemoryDB loads snapshot from path if it exists and saves one every
+// interval. Empty path disables snapshots.
+func NewMemoryDB(path string, interval time.Duration) (*MemoryDB, error) {
+	db := &MemoryDB{
+		Retention: DefaultRetention,
+		counters:  make(map[string]*entry),
+		history:   make(map[string]*keyHistory),
+		events:    make(map[string][]*Event),
+		subs:      make(map[string]map[chan *Event]struct{}),
+		path:      path,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	if path == "" {
+		close(db.done)
+		return db, nil
+	}
+
+	if err := db.load(); err != nil {
+		return nil, err
+	}
+
+	go db.snapshotLoop(interval)
+	return db, nil
+}
+
+// Stop saves the last snapshot.
+func (db *MemoryDB) Stop() error {
+	select {
+	case <-db.stop:
+		return nil
+	default:
+		close(db.stop)
+	}
+
+	<-db.done
+	if db.path == "" {
+		return nil
+	}
+
+	return db.Save()
+}
+
+// IncrementBy .
+func (db *MemoryDB) IncrementBy(ctx context.Context, key string, val int64, opts IncrOpts) (*Counter, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	e, exists := db.entry(key, now)
+
+	cur := int64(0)
+	exp := int64(0)
+	if exists {
+		cur, exp = e.Val, e.Exp
+	}
+
+	if overflows(cur, val) {
+		return nil, ErrNotInteger
+	}
+
+	next := cur + val
+	if !opts.allows(next) {
+		return nil, &BoundError{Val: cur}
+	}
+
+	if opts.TTL > 0 && (!opts.TTLOnCreate || !exists) {
+		exp = now.Add(opts.TTL).UnixNano()
+	}
+
+	c := &change{Op: OpIncr, Key: key, Val: next, Delta: val, Exp: exp, Time: now.UnixNano()}
+	if err := db.commit(c); err != nil {
+		return nil, err
+	}
+
+	return &Counter{Val: next, TTL: db.counters[key].ttl(now)}, nil
+}
+
+// IncrementMany checks every key before applying, so it is all or nothing.
+func (db *MemoryDB) IncrementMany(ctx context.Context, vals map[string]int64) (map[string]int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	res := make(map[string]int64, len(vals))
+
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	changes := make([]*change, 0, len(vals))
+	for _, k := range keys {
+		var cur, exp int64
+		if e, ok := db.entry(k, now); ok {
+			cur, exp = e.Val, e.Exp
+		}
+
+		if overflows(cur, vals[k]) {
+			return nil, fmt.Errorf("key %v: %w", k, ErrNotInteger)
+		}
+
+		res[k] = cur + vals[k]
+		changes = append(changes, &change{Op: OpIncr, Key: k, Val: res[k], Delta: vals[k], Exp: exp, Time: now.UnixNano()})
+	}
+
+	if err := db.commit(changes...); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Get .
+func (db *MemoryDB) Get(ctx context.Context, key string) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	e, ok := db.entry(key, time.Now())
+	if !ok {
+		return 0, ErrNotFound
+	}
+
+	return e.Val, nil
+}
+
+// GetMany returns values of existing keys only, missing ones are skipped.
+func (db *MemoryDB) GetMany(ctx context.Context, keys []string) (map[string]int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	m := make(map[string]int64, len(keys))
+
+	for _, k := range keys {
+		if e, ok := db.entry(k, now); ok {
+			m[k] = e.Val
+		}
+	}
+
+	return m, nil
+}
+
+// Set .
+func (db *MemoryDB) Set(ctx context.Context, key string, val int64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.commit(&change{Op: OpSet, Key: key, Val: val, Time: time.Now().UnixNano()})
+}
+
+// Delete .
+func (db *MemoryDB) Delete(ctx context.Context, key string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := db.entry(key, now); !ok {
+		return ErrNotFound
+	}
+
+	return db.commit(&change{Op: OpDelete, Key: key, Time: now.UnixNano()})
+}
+
+// Count .
+func (db *MemoryDB) Count(ctx context.Context, prefix string) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	var n int64
+
+	for k, e := range db.counters {
+		if strings.HasPrefix(k, prefix) && !e.expired(now) {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// Subscribe .
+func (db *MemoryDB) Subscribe(ctx context.Context, key string) (<-chan *Event, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	ch := make(chan *Event, subBuffer)
+	if db.subs[key] == nil {
+		db.subs[key] = make(map[chan *Event]struct{})
+	}
+	db.subs[key][ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+
+		db.mu.Lock()
+		defer db.mu.Unlock()
+
+		delete(db.subs[key], ch)
+		if len(db.subs[key]) == 0 {
+			delete(db.subs, key)
+		}
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// Events .
+func (db *MemoryDB) Events(ctx context.Context, key string, after int64) ([]*Event, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	evs := make([]*Event, 0)
+	for _, ev := range db.events[key] {
+		if ev.ID > after {
+			cp := *ev
+			evs = append(evs, &cp)
+		}
+	}
+
+	return evs, nil
+}
+
+// History .
+func (db *MemoryDB) History(ctx context.Context, key string, from, to time.Time, step time.Duration) ([]*Point, error) {
+	res, err := db.Retention.resolution(from, to, step)
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	buckets := make(map[int64]int64)
+	if h, ok := db.history[key]; ok {
+		src := h.Minute
+		if res == time.Hour {
+			src = h.Hour
+		}
+
+		for ts, delta := range src {
+			buckets[ts] = delta
+		}
+	}
+
+	return fillPoints(buckets, from, to, step), nil
+}
+
+// Save writes snapshot to a temporary file and renames it over the old one.
+func (db *MemoryDB) Save() error {
+	db.mu.Lock()
+	bs, err := json.Marshal(&snapshot{Counters: db.counters, History: db.history})
+	db.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(db.path, bs)
+}
+
+// entry returns live entry of key, expired one is removed. Must be called
+// under lock.
+func (db *MemoryDB) entry(key string, now time.Time) (*entry, bool) {
+	e, ok := db.counters[key]
+	if !ok {
+		return nil, false
+	}
+
+	if e.expired(now) {
+		delete(db.counters, key)
+		return nil, false
+	}
+
+	return e, true
+}
+
+// commit journals and applies changes. Must be called under lock.
+func (db *MemoryDB) commit(changes ...*change) error {
+	if db.journal != nil {
+		if err := db.journal(changes); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range changes {
+		db.apply(c)
+		db.notify(c)
+	}
+
+	return nil
+}
+
+// apply changes state without notifying subscribers, it is also used to
+// replay FileDB log.
+func (db *MemoryDB) apply(c *change) {
+	switch c.Op {
+	case OpDelete:
+		delete(db.counters, c.Key)
+	case OpSet:
+		db.counters[c.Key] = &entry{Val: c.Val}
+	case OpIncr:
+		db.counters[c.Key] = &entry{Val: c.Val, Exp: c.Exp}
+		db.record(c.Key, c.Delta, time.Unix(0, c.Time))
+	}
+}
+
+func (db *MemoryDB) record(key string, delta int64, at time.Time) {
+	h, ok := db.history[key]
+	if !ok {
+		h = &keyHistory{}
+		db.history[key] = h
+	}
+
+	h.Minute = addBucket(h.Minute, at, time.Minute, db.Retention.Minute, delta)
+	h.Hour = addBucket(h.Hour, at, time.Hour, db.Retention.Hour, delta)
+
+	if len(h.Minute) == 0 && len(h.Hour) == 0 {
+		delete(db.history, key)
+	}
+}
+
+// addBucket adds delta to bucket of at and drops buckets out of retention.
+func addBucket(buckets map[int64]int64, at time.Time, res, retention time.Duration, delta int64) map[int64]int64 {
+	if retention <= 0 {
+		return nil
+	}
+
+	if buckets == nil {
+		buckets = make(map[int64]int64)
+	}
+
+	buckets[at.Truncate(res).Unix()] += delta
+
+	return trimBuckets(buckets, retention)
+}
+
+// trimBuckets drops buckets out of retention, all of them when it is zero.
+func trimBuckets(buckets map[int64]int64, retention time.Duration) map[int64]int64 {
+	if retention <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-retention).Unix()
+	for ts := range buckets {
+		if ts < cutoff {
+			delete(buckets, ts)
+		}
+	}
+
+	return buckets
+}
+
+// trimHistory applies retention to restored history, it could be recorded
+// with another one.
+func (db *MemoryDB) trimHistory() {
+	for key, h := range db.history {
+		h.Minute = trimBuckets(h.Minute, db.Retention.Minute)
+		h.Hour = trimBuckets(h.Hour, db.Retention.Hour)
+
+		if len(h.Minute) == 0 && len(h.Hour) == 0 {
+			delete(db.history, key)
+		}
+	}
+}
+
+func (db *MemoryDB) notify(c *change) {
+	id := c.Time
+	// keep ids increasing even if clock is not
+	if id <= db.lastID {
+		id = db.lastID + 1
+	}
+	db.lastID = id
+
+	ev := &Event{ID: id, Op: c.Op, Key: c.Key, Val: c.Val, Delta: c.Delta}
+
+	evs := append(db.events[c.Key], ev)
+	if len(evs) > eventLogSize {
+		evs = evs[len(evs)-eventLogSize:]
+	}
+	db.events[c.Key] = evs
+
+	for ch := range db.subs[c.Key] {
+		cp := *ev
+		select {
+		case ch <- &cp:
+		default:
+			slog.Warn("subscriber is too slow, event dropped", "key", c.Key)
+		}
+	}
+}
+
+func (db *MemoryDB) snapshotLoop(interval time.Duration) {
+	defer close(db.done)
+
+	if interval <= 0 {
+		<-db.stop
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-ticker.C:
+			db.purge()
+			if err := db.Save(); err != nil {
+				slog.Error("cant save snapshot", "err", err)
+			}
+		}
+	}
+}
+
+// purge removes expired counters.
+func (db *MemoryDB) purge() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range db.counters {
+		if e.expired(now) {
+			delete(db.counters, k)
+		}
+	}
+}
+
+func (db *MemoryDB) load() error {
+	bs, err := os.ReadFile(db.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("cant read snapshot %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(bs, &snap); err != nil {
+		return fmt.Errorf("cant parse snapshot %w", err)
+	}
+
+	if snap.Counters != nil {
+		db.counters = snap.Counters
+	}
+
+	if snap.History != nil {
+		db.history = snap.History
+	}
+
+	return nil
+}
+
+func writeFileAtomic(path string, bs []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// rename is durable only after directory is synced
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
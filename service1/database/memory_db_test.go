@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryDB(t *testing.T) {
+	db, err := NewMemoryDB("", 0)
+	assert.NoError(t, err)
+	defer db.Stop()
+
+	ctx := context.Background()
+	min, max := int64(0), int64(10)
+
+	c, err := db.IncrementBy(ctx, "x", 4, IncrOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, &Counter{Val: 4, TTL: -1}, c)
+
+	_, err = db.IncrementBy(ctx, "x", -5, IncrOpts{Min: &min, Max: &max})
+	assert.Equal(t, &BoundError{Val: 4}, err)
+	assert.ErrorIs(t, err, ErrOutOfBounds)
+
+	c, err = db.IncrementBy(ctx, "x", 6, IncrOpts{Min: &min, Max: &max})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), c.Val)
+
+	c, err = db.IncrementBy(ctx, "ttl", 1, IncrOpts{TTL: time.Minute, TTLOnCreate: true})
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Minute, c.TTL, float64(time.Second))
+
+	c, err = db.IncrementBy(ctx, "ttl", 1, IncrOpts{TTL: time.Hour, TTLOnCreate: true})
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Minute, c.TTL, float64(time.Second))
+
+	_, err = db.IncrementBy(ctx, "short", 1, IncrOpts{TTL: time.Millisecond})
+	assert.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = db.Get(ctx, "short")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, db.Set(ctx, "big", 1<<62))
+	_, err = db.IncrementMany(ctx, map[string]int64{"x": 1, "big": 1 << 62})
+	assert.ErrorIs(t, err, ErrNotInteger)
+
+	m, err := db.GetMany(ctx, []string{"x", "big", "missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"x": 10, "big": 1 << 62}, m)
+
+	n, err := db.Count(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	assert.NoError(t, db.Delete(ctx, "big"))
+	assert.ErrorIs(t, db.Delete(ctx, "big"), ErrNotFound)
+
+	now := time.Now()
+	points, err := db.History(ctx, "x", now.Add(-time.Hour), now.Add(time.Minute), time.Hour)
+	assert.NoError(t, err)
+
+	var total int64
+	for _, p := range points {
+		total += p.Delta
+	}
+	assert.Equal(t, int64(10), total)
+
+	// hour step is built from minute buckets without hour retention
+	db.Retention.Hour = 0
+	_, err = db.IncrementBy(ctx, "minutes", 3, IncrOpts{})
+	assert.NoError(t, err)
+
+	points, err = db.History(ctx, "minutes", now.Add(-time.Hour), now.Add(time.Minute), time.Hour)
+	assert.NoError(t, err)
+
+	total = 0
+	for _, p := range points {
+		total += p.Delta
+	}
+	assert.Equal(t, int64(3), total)
+}
+
+func TestMemoryDB_Subscribe(t *testing.T) {
+	db, err := NewMemoryDB("", 0)
+	assert.NoError(t, err)
+	defer db.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	evs, err := db.Subscribe(ctx, "x")
+	assert.NoError(t, err)
+
+	_, err = db.IncrementBy(ctx, "x", 2, IncrOpts{})
+	assert.NoError(t, err)
+
+	ev := <-evs
+	assert.Equal(t, OpIncr, ev.Op)
+	assert.Equal(t, int64(2), ev.Val)
+
+	replay, err := db.Events(ctx, "x", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Event{ev}, replay)
+
+	cancel()
+	_, ok := <-evs
+	assert.False(t, ok)
+}
+
+func TestMemoryDB_Snapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	db, err := NewMemoryDB(path, time.Hour)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = db.IncrementBy(ctx, "x", 3, IncrOpts{TTL: time.Hour})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Stop())
+
+	db, err = NewMemoryDB(path, time.Hour)
+	assert.NoError(t, err)
+	defer db.Stop()
+
+	val, err := db.Get(ctx, "x")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), val)
+
+	c, err := db.IncrementBy(ctx, "x", 1, IncrOpts{})
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour, c.TTL, float64(time.Second))
+}
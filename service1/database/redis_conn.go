@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	minReconnectWait = 100 * time.Millisecond
+	maxReconnectWait = 30 * time.Second
+	pingTimeout      = 2 * time.Second
+)
+
+// Connect pings redis. When it is unreachable db is marked unavailable and
+// reconnects in background, the ping error is returned for logging only.
+func (db *RedisDB) Connect(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	err := db.Client.Ping(ctx).Err()
+	if err != nil {
+		db.reconnect()
+	}
+
+	return err
+}
+
+// Ping reports ErrUnavailable while db reconnects, otherwise pings redis.
+func (db *RedisDB) Ping(ctx context.Context) error {
+	if err := db.available(); err != nil {
+		return err
+	}
+
+	return db.check(ctx, db.Client.Ping(ctx).Err())
+}
+
+func (db *RedisDB) available() error {
+	if atomic.LoadInt32(&db.down) == 1 {
+		return ErrUnavailable
+	}
+
+	return nil
+}
+
+// check marks db unavailable on connection errors and wraps them into
+// ErrUnavailable. Errors of canceled requests are not connection errors.
+func (db *RedisDB) check(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	var nerr net.Error
+	if !errors.As(err, &nerr) && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	db.reconnect()
+	return fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
+
+// reconnect pings redis with exponential backoff until it answers or db is
+// stopped. Only one reconnect loop runs at a time.
+func (db *RedisDB) reconnect() {
+	if !atomic.CompareAndSwapInt32(&db.down, 0, 1) {
+		return
+	}
+
+	go func() {
+		wait := minReconnectWait
+
+		for {
+			select {
+			case <-db.stop:
+				return
+			case <-time.After(wait):
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+			err := db.Client.Ping(ctx).Err()
+			cancel()
+
+			if err == nil {
+				atomic.StoreInt32(&db.down, 0)
+				slog.Info("redis is available")
+				return
+			}
+
+			slog.Warn("redis is unavailable", "retry_in", wait, "err", err)
+
+			wait *= 2
+			if wait > maxReconnectWait {
+				wait = maxReconnectWait
+			}
+		}
+	}()
+}
@@ -2,42 +2,472 @@ package database
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
-// // DB .
-// type DB interface {
-// 	IncrementBy(context.Context, string, int64) (int64, error)
-// }
+const maxTxRetries = 10
 
 // RedisDB .
 type RedisDB struct {
-	Client *redis.Client
+	// Client is standalone, sentinel failover or cluster client.
+	Client redis.UniversalClient
+	// Retention of increments history, zero disables resolution.
+	Retention Retention
+
+	// down is 1 while redis is unreachable and db reconnects.
+	down int32
+	stop chan struct{}
 }
 
 // NewDB .
-func NewDB(client *redis.Client) *RedisDB {
-	return &RedisDB{Client: client}
+func NewDB(client redis.UniversalClient) *RedisDB {
+	client.AddHook(metricsHook{})
+	client.AddHook(tracingHook{})
+	return &RedisDB{Client: client, Retention: DefaultRetention, stop: make(chan struct{})}
 }
 
 // Stop .
 func (db *RedisDB) Stop() error {
+	select {
+	case <-db.stop:
+	default:
+		close(db.stop)
+	}
+
 	return db.Client.Close()
 }
 
 // IncrementBy .
-func (db *RedisDB) IncrementBy(ctx context.Context, key string, val int64) (int64, error) {
+func (db *RedisDB) IncrementBy(ctx context.Context, key string, val int64, opts IncrOpts) (*Counter, error) {
+	return db.increment(ctx, key, val, opts, nil)
+}
 
-	pipe := db.Client.TxPipeline()
-	defer pipe.Close()
+func (db *RedisDB) increment(ctx context.Context, key string, val int64, opts IncrOpts, q *keyQuota) (*Counter, error) {
+	if err := db.available(); err != nil {
+		return nil, err
+	}
+
+	c, err := db.incrementBy(ctx, key, val, opts, q)
+	if err != nil {
+		return nil, db.check(ctx, err)
+	}
 
-	incr := pipe.IncrBy(ctx, key, val)
+	db.record(ctx, key, val)
+	return c, nil
+}
+
+func (db *RedisDB) incrementBy(ctx context.Context, key string, val int64, opts IncrOpts, q *keyQuota) (*Counter, error) {
+	if opts.Min != nil || opts.Max != nil {
+		return db.incrementByBounded(ctx, key, val, opts, q)
+	}
+
+	if opts.TTL > 0 && opts.TTLOnCreate {
+		return db.incrementByOnCreate(ctx, key, val, opts.TTL, q)
+	}
 
-	_, err := pipe.Exec(ctx)
+	var incr *redis.Cmd
+	var ttl *redis.DurationCmd
+
+	err := db.watchQuota(ctx, q, []string{key}, func(pipe redis.Pipeliner) {
+		incr = queueChange(ctx, pipe, OpIncr, key, val)
+		if opts.TTL > 0 {
+			expire(ctx, pipe, key, opts.TTL)
+		}
+		ttl = pipe.PTTL(ctx, key)
+	})
 	if err != nil {
+		return nil, redisErr(err)
+	}
+
+	return counter(incr, ttl)
+}
+
+// incrementByOnCreate sets ttl only if key does not exist before increment,
+// key is watched so concurrent creation retries the transaction.
+func (db *RedisDB) incrementByOnCreate(ctx context.Context, key string, val int64, exp time.Duration, q *keyQuota) (*Counter, error) {
+	var incr *redis.Cmd
+	var ttl *redis.DurationCmd
+
+	txf := func(tx *redis.Tx) error {
+		n, err := tx.Exists(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+
+		track, err := q.claim(ctx, tx, []string{key})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			incr = queueChange(ctx, pipe, OpIncr, key, val)
+			if n == 0 {
+				expire(ctx, pipe, key, exp)
+			}
+			ttl = pipe.PTTL(ctx, key)
+			track(pipe)
+			return nil
+		})
+
+		return err
+	}
+
+	if err := db.watch(ctx, txf, q.watched(key)...); err != nil {
+		return nil, redisErr(err)
+	}
+
+	return counter(incr, ttl)
+}
+
+// incrementByBounded checks bounds against watched current value in go, so
+// values and bounds are compared as int64 without loss of precision.
+func (db *RedisDB) incrementByBounded(ctx context.Context, key string, val int64, opts IncrOpts, q *keyQuota) (*Counter, error) {
+	var incr *redis.Cmd
+	var ttl *redis.DurationCmd
+
+	txf := func(tx *redis.Tx) error {
+		var cur int64
+
+		s, err := tx.Get(ctx, key).Result()
+		exists := err == nil
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		if exists {
+			if cur, err = strconv.ParseInt(s, 10, 64); err != nil {
+				return ErrNotInteger
+			}
+		}
+
+		if overflows(cur, val) {
+			return ErrNotInteger
+		}
+
+		if !opts.allows(cur + val) {
+			return &BoundError{Val: cur}
+		}
+
+		track, err := q.claim(ctx, tx, []string{key})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			incr = queueChange(ctx, pipe, OpIncr, key, val)
+			if opts.TTL > 0 && (!opts.TTLOnCreate || !exists) {
+				expire(ctx, pipe, key, opts.TTL)
+			}
+			ttl = pipe.PTTL(ctx, key)
+			track(pipe)
+			return nil
+		})
+
+		return err
+	}
+
+	if err := db.watch(ctx, txf, q.watched(key)...); err != nil {
+		return nil, redisErr(err)
+	}
+
+	return counter(incr, ttl)
+}
+
+// counter reads results of change and PTTL commands.
+func counter(incr *redis.Cmd, ttl *redis.DurationCmd) (*Counter, error) {
+	n, err := incr.Int64()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Counter{Val: n, TTL: ttl.Val()}, nil
+}
+
+// watch runs txf with keys watched, retrying it when they change.
+func (db *RedisDB) watch(ctx context.Context, txf func(*redis.Tx) error, keys ...string) error {
+	for i := 0; i < maxTxRetries; i++ {
+		err := db.Client.Watch(ctx, txf, keys...)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+
+		return err
+	}
+
+	return ErrTxConflict
+}
+
+// pttl converts PTTL reply keeping -1 and -2 as they are like go-redis does.
+func pttl(ms int64) time.Duration {
+	if ms < 0 {
+		return time.Duration(ms)
+	}
+
+	return time.Duration(ms) * time.Millisecond
+}
+
+// redisErr maps redis errors to package ones.
+func redisErr(err error) error {
+	var rerr redis.Error
+	if errors.As(err, &rerr) && strings.Contains(rerr.Error(), "not an integer") {
+		return ErrNotInteger
+	}
+
+	return err
+}
+
+// IncrementMany increments all keys in one MULTI/EXEC. Keys are watched and
+// checked before the transaction so that it either applies fully or not at all.
+func (db *RedisDB) IncrementMany(ctx context.Context, vals map[string]int64) (map[string]int64, error) {
+	return db.incrementMany(ctx, vals, nil)
+}
+
+func (db *RedisDB) incrementMany(ctx context.Context, vals map[string]int64, q *keyQuota) (map[string]int64, error) {
+	res := make(map[string]int64, len(vals))
+	if len(vals) == 0 {
+		return res, nil
+	}
+
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+
+	if db.cluster() && !sameSlot(q.watched(keys...)) {
+		return nil, ErrCrossSlot
+	}
+
+	if err := db.available(); err != nil {
+		return nil, err
+	}
+
+	incrs := make(map[string]*redis.Cmd, len(vals))
+
+	txf := func(tx *redis.Tx) error {
+		olds, err := tx.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+
+		for i, v := range olds {
+			s, ok := v.(string)
+			if !ok {
+				continue
+			}
+
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || overflows(n, vals[keys[i]]) {
+				return fmt.Errorf("key %v: %w", keys[i], ErrNotInteger)
+			}
+		}
+
+		track, err := q.claim(ctx, tx, keys)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, k := range keys {
+				incrs[k] = queueChange(ctx, pipe, OpIncr, k, vals[k])
+			}
+			track(pipe)
+			return nil
+		})
+
+		return err
+	}
+
+	if err := db.watch(ctx, txf, q.watched(keys...)...); err != nil {
+		return nil, db.check(ctx, err)
+	}
+
+	for k, incr := range incrs {
+		n, err := incr.Int64()
+		if err != nil {
+			return nil, err
+		}
+
+		res[k] = n
+		db.record(ctx, k, vals[k])
+	}
+
+	return res, nil
+}
+
+// Count scans keyspace, so it is linear in number of keys. In cluster mode
+// every master is scanned.
+func (db *RedisDB) Count(ctx context.Context, prefix string) (int64, error) {
+	if err := db.available(); err != nil {
 		return 0, err
 	}
 
-	return incr.Val(), err
+	cc, ok := db.Client.(*redis.ClusterClient)
+	if !ok {
+		return count(ctx, db.Client, prefix)
+	}
+
+	var n int64
+	var mu sync.Mutex
+
+	err := cc.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		c, err := count(ctx, client, prefix)
+
+		mu.Lock()
+		n += c
+		mu.Unlock()
+
+		return err
+	})
+
+	return n, err
+}
+
+func count(ctx context.Context, client redis.UniversalClient, prefix string) (int64, error) {
+	var n int64
+
+	iter := client.Scan(ctx, 0, escapeGlob(prefix)+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		n++
+	}
+
+	return n, iter.Err()
+}
+
+func (db *RedisDB) cluster() bool {
+	_, ok := db.Client.(*redis.ClusterClient)
+	return ok
+}
+
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func overflows(a, b int64) bool {
+	c := a + b
+	return (c > a) != (b > 0)
+}
+
+// expire uses PEXPIRE only when ttl is not a whole number of seconds.
+func expire(ctx context.Context, pipe redis.Pipeliner, key string, ttl time.Duration) {
+	if ttl%time.Second == 0 {
+		pipe.Expire(ctx, key, ttl)
+		return
+	}
+
+	pipe.PExpire(ctx, key, ttl)
+}
+
+// Get .
+func (db *RedisDB) Get(ctx context.Context, key string) (int64, error) {
+	if err := db.available(); err != nil {
+		return 0, err
+	}
+
+	val, err := db.Client.Get(ctx, key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, ErrNotFound
+	}
+
+	return val, db.check(ctx, err)
+}
+
+// GetMany returns values of existing keys only, missing ones are skipped.
+// Keys are read in a pipeline rather than MGET, so they may be in different
+// cluster slots.
+func (db *RedisDB) GetMany(ctx context.Context, keys []string) (map[string]int64, error) {
+	m := make(map[string]int64, len(keys))
+	if len(keys) == 0 {
+		return m, nil
+	}
+
+	if err := db.available(); err != nil {
+		return nil, err
+	}
+
+	pipe := db.Client.Pipeline()
+	defer pipe.Close()
+
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, k := range keys {
+		cmds[i] = pipe.Get(ctx, k)
+	}
+
+	// redis.Nil of missing keys is checked per command
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, db.check(ctx, err)
+	}
+
+	for i, cmd := range cmds {
+		n, err := cmd.Int64()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("not integer value for key %v %w", keys[i], err)
+		}
+
+		m[keys[i]] = n
+	}
+
+	return m, nil
+}
+
+// Set .
+func (db *RedisDB) Set(ctx context.Context, key string, val int64) error {
+	return db.set(ctx, key, val, nil)
+}
+
+func (db *RedisDB) set(ctx context.Context, key string, val int64, q *keyQuota) error {
+	if err := db.available(); err != nil {
+		return err
+	}
+
+	err := db.watchQuota(ctx, q, []string{key}, func(pipe redis.Pipeliner) {
+		queueChange(ctx, pipe, OpSet, key, val)
+	})
+
+	return db.check(ctx, err)
+}
+
+// Delete .
+func (db *RedisDB) Delete(ctx context.Context, key string) error {
+	return db.delete(ctx, key, nil)
+}
+
+func (db *RedisDB) delete(ctx context.Context, key string, q *keyQuota) error {
+	if err := db.available(); err != nil {
+		return err
+	}
+
+	pipe := db.Client.TxPipeline()
+	defer pipe.Close()
+
+	del := queueChange(ctx, pipe, OpDelete, key, 0)
+	q.untrack(ctx, pipe, key)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return db.check(ctx, err)
+	}
+
+	if n, _ := del.Int64(); n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
 }
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"service1/logger"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// eventLogSize is number of recent events kept per key for replay.
+	eventLogSize = 100
+	eventLogTTL  = time.Hour
+)
+
+// changeScript applies change ARGV[1] with value ARGV[2] to counter KEYS[1]
+// and, within the same script, publishes event of it to channel ARGV[3] and
+// appends it to replay log KEYS[3]. Event id is next value of sequence
+// KEYS[2], so ids of key are ordered whichever instance changes it. Value is
+// kept as redis string in event json, lua numbers lose precision beyond 2^53.
+// It returns new value, or number of deleted keys for delete.
+var changeScript = redis.NewScript(`
+local op, val = ARGV[1], ARGV[2]
+local res, delta
+
+if op == 'incr' then
+	redis.call('INCRBY', KEYS[1], val)
+	delta = val
+	val = redis.call('GET', KEYS[1])
+	res = val
+elseif op == 'set' then
+	redis.call('SET', KEYS[1], val)
+	res = val
+else
+	res = redis.call('DEL', KEYS[1])
+	if res == 0 then
+		return 0
+	end
+	val = '0'
+end
+
+local id = redis.call('INCR', KEYS[2])
+local ev = '{"id":' .. id .. ',"op":' .. cjson.encode(op) .. ',"key":' .. cjson.encode(KEYS[1]) .. ',"val":' .. val
+if delta then
+	ev = ev .. ',"delta":' .. delta
+end
+ev = ev .. '}'
+
+redis.call('PUBLISH', ARGV[3], ev)
+redis.call('LPUSH', KEYS[3], ev)
+redis.call('LTRIM', KEYS[3], 0, tonumber(ARGV[4]) - 1)
+redis.call('PEXPIRE', KEYS[3], ARGV[5])
+
+return res
+`)
+
+func eventsChannel(key string) string {
+	return auxKey("counter-events", key)
+}
+
+func eventsLog(key string) string {
+	return auxKey("counter-events-log", key)
+}
+
+// eventsSeq never expires, so ids keep growing after key is deleted.
+func eventsSeq(key string) string {
+	return auxKey("counter-events-seq", key)
+}
+
+// queueChange queues op of key with its event into pipe, which is usually
+// MULTI of the write. Value of returned cmd is new value of counter, or number
+// of deleted keys for delete.
+func queueChange(ctx context.Context, pipe redis.Pipeliner, op, key string, val int64) *redis.Cmd {
+	return changeScript.Eval(ctx, pipe,
+		[]string{key, eventsSeq(key), eventsLog(key)},
+		op, val, eventsChannel(key), eventLogSize, eventLogTTL.Milliseconds(),
+	)
+}
+
+// Subscribe .
+func (db *RedisDB) Subscribe(ctx context.Context, key string) (<-chan *Event, error) {
+	if err := db.available(); err != nil {
+		return nil, err
+	}
+
+	sub := db.Client.Subscribe(ctx, eventsChannel(key))
+
+	// wait for confirmation so no event is missed after return
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, db.check(ctx, err)
+	}
+
+	ch := make(chan *Event)
+	msgs := sub.Channel()
+
+	go func() {
+		defer close(ch)
+		defer sub.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+
+				var ev Event
+				if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+					logger.FromContext(ctx).Warn("cant unmarshal event", "key", key, "err", err)
+					continue
+				}
+
+				select {
+				case ch <- &ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Events .
+func (db *RedisDB) Events(ctx context.Context, key string, after int64) ([]*Event, error) {
+	if err := db.available(); err != nil {
+		return nil, err
+	}
+
+	strs, err := db.Client.LRange(ctx, eventsLog(key), 0, -1).Result()
+	if err != nil {
+		return nil, db.check(ctx, err)
+	}
+
+	evs := make([]*Event, 0, len(strs))
+	for i := len(strs) - 1; i >= 0; i-- {
+		var ev Event
+		if err := json.Unmarshal([]byte(strs[i]), &ev); err != nil {
+			return nil, err
+		}
+
+		if ev.ID > after {
+			evs = append(evs, &ev)
+		}
+	}
+
+	return evs, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisDB_Events(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	// two service instances sharing redis
+	newDB := func() *RedisDB {
+		db := NewDB(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+		t.Cleanup(func() { db.Stop() })
+		return db
+	}
+	db1, db2 := newDB(), newDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	live, err := db2.Subscribe(ctx, "x")
+	assert.NoError(t, err)
+
+	big := int64(1<<53 + 1)
+
+	_, err = db1.IncrementBy(ctx, "x", 2, IncrOpts{})
+	assert.NoError(t, err)
+	_, err = db2.IncrementMany(ctx, map[string]int64{"x": 3})
+	assert.NoError(t, err)
+	assert.NoError(t, db1.Set(ctx, "x", big))
+	assert.NoError(t, db2.Delete(ctx, "x"))
+	assert.ErrorIs(t, db1.Delete(ctx, "x"), ErrNotFound)
+	_, err = db2.IncrementBy(ctx, "x", 1, IncrOpts{TTL: time.Minute, TTLOnCreate: true})
+	assert.NoError(t, err)
+
+	expected := []*Event{
+		{ID: 1, Op: OpIncr, Key: "x", Val: 2, Delta: 2},
+		{ID: 2, Op: OpIncr, Key: "x", Val: 5, Delta: 3},
+		{ID: 3, Op: OpSet, Key: "x", Val: big},
+		{ID: 4, Op: OpDelete, Key: "x"},
+		{ID: 5, Op: OpIncr, Key: "x", Val: 1, Delta: 1},
+	}
+
+	for _, ev := range expected {
+		select {
+		case got := <-live:
+			assert.Equal(t, ev, got)
+		case <-ctx.Done():
+			t.Fatal("event not received")
+		}
+	}
+
+	evs, err := db1.Events(ctx, "x", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, expected[2:], evs)
+
+	// failed increment publishes nothing
+	assert.NoError(t, redisServer.Set("text", "oops"))
+	_, err = db1.IncrementBy(ctx, "text", 1, IncrOpts{})
+	assert.ErrorIs(t, err, ErrNotInteger)
+
+	evs, err = db1.Events(ctx, "text", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, evs)
+}
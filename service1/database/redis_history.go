@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"service1/logger"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// recordScript adds delta ARGV[1] to bucket of every (hash, index) key pair
+// and drops buckets older than cutoff. ARGV holds bucket, cutoff and ttl in ms
+// for each pair after the delta.
+var recordScript = redis.NewScript(`
+for i = 0, #KEYS / 2 - 1 do
+	local h, z = KEYS[i * 2 + 1], KEYS[i * 2 + 2]
+	local bucket, cutoff, ttl = ARGV[i * 3 + 2], ARGV[i * 3 + 3], ARGV[i * 3 + 4]
+
+	redis.call('HINCRBY', h, bucket, ARGV[1])
+	redis.call('ZADD', z, bucket, bucket)
+
+	local old = redis.call('ZRANGEBYSCORE', z, '-inf', '(' .. cutoff)
+	if #old > 0 then
+		redis.call('HDEL', h, unpack(old))
+		redis.call('ZREMRANGEBYSCORE', z, '-inf', '(' .. cutoff)
+	end
+
+	redis.call('PEXPIRE', h, ttl)
+	redis.call('PEXPIRE', z, ttl)
+end
+return 1
+`)
+
+func historyKeys(key string, res time.Duration) (string, string) {
+	suffix := ":m"
+	if res == time.Hour {
+		suffix = ":h"
+	}
+
+	return auxKey("counter-history"+suffix, key), auxKey("counter-history-idx"+suffix, key)
+}
+
+// record adds delta to minute and hour buckets of key. Counter is already
+// changed at this point, so errors are only reported.
+func (db *RedisDB) record(ctx context.Context, key string, delta int64) {
+	now := time.Now()
+
+	keys := make([]string, 0, 4)
+	args := []interface{}{delta}
+
+	for _, r := range []struct {
+		res       time.Duration
+		retention time.Duration
+	}{
+		{time.Minute, db.Retention.Minute},
+		{time.Hour, db.Retention.Hour},
+	} {
+		if r.retention <= 0 {
+			continue
+		}
+
+		h, z := historyKeys(key, r.res)
+		keys = append(keys, h, z)
+		args = append(args,
+			now.Truncate(r.res).Unix(),
+			now.Add(-r.retention).Unix(),
+			r.retention.Milliseconds())
+	}
+
+	if len(keys) == 0 {
+		return
+	}
+
+	if err := recordScript.Run(ctx, db.Client, keys, args...).Err(); err != nil {
+		logger.FromContext(ctx).Warn("cant record history", "key", key, "err", err)
+	}
+}
+
+// History .
+func (db *RedisDB) History(ctx context.Context, key string, from, to time.Time, step time.Duration) ([]*Point, error) {
+	res, err := db.Retention.resolution(from, to, step)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.available(); err != nil {
+		return nil, err
+	}
+
+	h, z := historyKeys(key, res)
+
+	fields, err := db.Client.ZRangeByScore(ctx, z, &redis.ZRangeBy{
+		Min: strconv.FormatInt(from.Truncate(step).Unix(), 10),
+		Max: "(" + strconv.FormatInt(to.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, db.check(ctx, err)
+	}
+
+	buckets := make(map[int64]int64, len(fields))
+	if len(fields) > 0 {
+		vals, err := db.Client.HMGet(ctx, h, fields...).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for i, v := range vals {
+			s, ok := v.(string)
+			if !ok {
+				continue
+			}
+
+			ts, err := strconv.ParseInt(fields[i], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			delta, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			buckets[ts] = delta
+		}
+	}
+
+	return fillPoints(buckets, from, to, step), nil
+}
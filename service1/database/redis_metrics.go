@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"service1/metrics"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// metricsHook counts failed redis commands, redis.Nil of missing keys and
+// transaction conflicts are expected and not counted.
+type metricsHook struct{}
+
+var _ redis.Hook = metricsHook{}
+
+func (metricsHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (metricsHook) AfterProcess(_ context.Context, cmd redis.Cmder) error {
+	countErr(cmd)
+	return nil
+}
+
+func (metricsHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (metricsHook) AfterProcessPipeline(_ context.Context, cmds []redis.Cmder) error {
+	for _, cmd := range cmds {
+		countErr(cmd)
+	}
+	return nil
+}
+
+func countErr(cmd redis.Cmder) {
+	if cmdErr(cmd) != nil {
+		metrics.RedisErrors.WithLabelValues(cmd.Name()).Inc()
+	}
+}
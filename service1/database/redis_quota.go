@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var _ QuotaDB = (*RedisDB)(nil)
+
+// keyQuota tracks keys of a group in redis set and limits their number. Set
+// shares hash tag of the group, so it is in the same cluster slot as keys of
+// tenant. Members of expired keys are dropped once quota is reached.
+type keyQuota struct {
+	set string
+	// max of zero only tracks keys.
+	max int64
+}
+
+// WithQuota .
+func (db *RedisDB) WithQuota(group string, max int64) DB {
+	return &redisQuotaDB{RedisDB: db, q: &keyQuota{set: auxKey("quota-keys", group), max: max}}
+}
+
+// redisQuotaDB writes through RedisDB tracking created keys in quota set in
+// the same transaction.
+type redisQuotaDB struct {
+	*RedisDB
+	q *keyQuota
+}
+
+func (db *redisQuotaDB) IncrementBy(ctx context.Context, key string, val int64, opts IncrOpts) (*Counter, error) {
+	return db.increment(ctx, key, val, opts, db.q)
+}
+
+func (db *redisQuotaDB) IncrementMany(ctx context.Context, vals map[string]int64) (map[string]int64, error) {
+	return db.incrementMany(ctx, vals, db.q)
+}
+
+func (db *redisQuotaDB) Set(ctx context.Context, key string, val int64) error {
+	return db.set(ctx, key, val, db.q)
+}
+
+func (db *redisQuotaDB) Delete(ctx context.Context, key string) error {
+	return db.delete(ctx, key, db.q)
+}
+
+// watched returns keys with quota set, which has to be watched too.
+func (q *keyQuota) watched(keys ...string) []string {
+	if q == nil {
+		return keys
+	}
+
+	return append(keys, q.set)
+}
+
+// claim checks inside of WATCH of quota set that keys not tracked yet fit
+// into quota. Returned func tracks them in transaction of the write.
+func (q *keyQuota) claim(ctx context.Context, tx *redis.Tx, keys []string) (func(redis.Pipeliner), error) {
+	if q == nil {
+		return func(redis.Pipeliner) {}, nil
+	}
+
+	if q.max <= 0 {
+		return func(pipe redis.Pipeliner) { q.track(ctx, pipe, keys, nil) }, nil
+	}
+
+	pipe := tx.Pipeline()
+	defer pipe.Close()
+
+	members := make([]*redis.BoolCmd, len(keys))
+	for i, k := range keys {
+		members[i] = pipe.SIsMember(ctx, q.set, k)
+	}
+	card := pipe.SCard(ctx, q.set)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	created := make([]string, 0, len(keys))
+	for i, m := range members {
+		if !m.Val() {
+			created = append(created, keys[i])
+		}
+	}
+
+	var stale []string
+	if n := card.Val() + int64(len(created)); n > q.max && len(created) > 0 {
+		var err error
+		if stale, err = q.stale(ctx, tx); err != nil {
+			return nil, err
+		}
+
+		if n-int64(len(stale)) > q.max {
+			return nil, ErrQuotaExceeded
+		}
+	}
+
+	return func(pipe redis.Pipeliner) { q.track(ctx, pipe, created, stale) }, nil
+}
+
+// stale returns members of quota set whose keys are gone, like expired ones.
+func (q *keyQuota) stale(ctx context.Context, tx *redis.Tx) ([]string, error) {
+	members, err := tx.SMembers(ctx, q.set).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := tx.Pipeline()
+	defer pipe.Close()
+
+	exists := make([]*redis.IntCmd, len(members))
+	for i, m := range members {
+		exists[i] = pipe.Exists(ctx, m)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for i, e := range exists {
+		if e.Val() == 0 {
+			stale = append(stale, members[i])
+		}
+	}
+
+	return stale, nil
+}
+
+func (q *keyQuota) track(ctx context.Context, pipe redis.Pipeliner, created, stale []string) {
+	if len(stale) > 0 {
+		pipe.SRem(ctx, q.set, toArgs(stale)...)
+	}
+
+	if len(created) > 0 {
+		pipe.SAdd(ctx, q.set, toArgs(created)...)
+	}
+}
+
+func (q *keyQuota) untrack(ctx context.Context, pipe redis.Pipeliner, key string) {
+	if q != nil {
+		pipe.SRem(ctx, q.set, key)
+	}
+}
+
+// watchQuota runs write in transaction tracking keys in quota set. Quota set
+// is watched only when quota is limited, otherwise keys are just added.
+func (db *RedisDB) watchQuota(ctx context.Context, q *keyQuota, keys []string, write func(redis.Pipeliner)) error {
+	if q == nil || q.max <= 0 {
+		pipe := db.Client.TxPipeline()
+		defer pipe.Close()
+
+		write(pipe)
+		if q != nil {
+			q.track(ctx, pipe, keys, nil)
+		}
+
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+
+	return db.watch(ctx, func(tx *redis.Tx) error {
+		track, err := q.claim(ctx, tx, keys)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			write(pipe)
+			track(pipe)
+			return nil
+		})
+
+		return err
+	}, q.set)
+}
+
+func toArgs(strs []string) []interface{} {
+	args := make([]interface{}, len(strs))
+	for i, s := range strs {
+		args[i] = s
+	}
+
+	return args
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"service1/tracing"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingHook traces every redis command or pipeline as a client span.
+type tracingHook struct{}
+
+var _ redis.Hook = tracingHook{}
+
+func (tracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, _ = tracing.Tracer().Start(ctx, "redis."+cmd.Name(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "redis")),
+	)
+	return ctx, nil
+}
+
+func (tracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	tracing.End(trace.SpanFromContext(ctx), cmdErr(cmd))
+	return nil
+}
+
+func (tracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	ctx, _ = tracing.Tracer().Start(ctx, "redis.pipeline",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.Int("db.redis.num_cmd", len(cmds)),
+		),
+	)
+	return ctx, nil
+}
+
+func (tracingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if err = cmdErr(cmd); err != nil {
+			break
+		}
+	}
+
+	tracing.End(trace.SpanFromContext(ctx), err)
+	return nil
+}
+
+// cmdErr returns error of failed command, missing keys and transaction
+// conflicts are expected outcomes.
+func cmdErr(cmd redis.Cmder) error {
+	err := cmd.Err()
+	if errors.Is(err, redis.Nil) || errors.Is(err, redis.TxFailedErr) {
+		return nil
+	}
+
+	return err
+}
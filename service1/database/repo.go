@@ -1,8 +1,155 @@
 package database
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotFound .
+	ErrNotFound = errors.New("counter not found")
+	// ErrNotInteger .
+	ErrNotInteger = errors.New("value is not an integer or out of range")
+	// ErrOutOfBounds .
+	ErrOutOfBounds = errors.New("counter bound would be violated")
+	// ErrBadStep .
+	ErrBadStep = errors.New("step must be positive multiple of a minute and fit into range")
+	// ErrTxConflict .
+	ErrTxConflict = errors.New("too many concurrent updates, transaction aborted")
+	// ErrUnavailable .
+	ErrUnavailable = errors.New("storage is unavailable")
+)
+
+// IncrOpts .
+type IncrOpts struct {
+	// TTL is applied to the counter when positive.
+	TTL time.Duration
+	// TTLOnCreate applies TTL only when the counter is created by this increment.
+	TTLOnCreate bool
+	// Min and Max reject increments leaving the counter outside of bounds.
+	Min, Max *int64
+}
+
+// allows reports whether counter value n is within bounds.
+func (o IncrOpts) allows(n int64) bool {
+	return (o.Min == nil || n >= *o.Min) && (o.Max == nil || n <= *o.Max)
+}
+
+// BoundError is returned when increment is rejected by IncrOpts bounds.
+type BoundError struct {
+	// Val is current, unchanged counter value.
+	Val int64
+}
+
+func (e *BoundError) Error() string {
+	return ErrOutOfBounds.Error()
+}
+
+// Unwrap .
+func (e *BoundError) Unwrap() error {
+	return ErrOutOfBounds
+}
+
+// Counter .
+type Counter struct {
+	Val int64
+	// TTL is remaining time to live, negative when counter never expires.
+	TTL time.Duration
+}
+
+// Event operations.
+const (
+	OpIncr     = "incr"
+	OpSet      = "set"
+	OpDelete   = "del"
+	OpSnapshot = "snapshot"
+)
+
+// Event describes a counter change.
+type Event struct {
+	// ID grows with every change of key, it is used as SSE event id.
+	ID    int64  `json:"id"`
+	Op    string `json:"op"`
+	Key   string `json:"key"`
+	Val   int64  `json:"val"`
+	Delta int64  `json:"delta,omitempty"`
+}
+
+// Retention is how long history buckets of each resolution are kept.
+type Retention struct {
+	Minute time.Duration
+	Hour   time.Duration
+}
+
+// DefaultRetention .
+var DefaultRetention = Retention{Minute: 24 * time.Hour, Hour: 30 * 24 * time.Hour}
+
+// MaxHistoryPoints .
+const MaxHistoryPoints = 10000
+
+// Point is sum of increments made during [Time, Time+step).
+type Point struct {
+	Time  time.Time `json:"t"`
+	Delta int64     `json:"delta"`
+}
 
 // DB .
 type DB interface {
-	IncrementBy(context.Context, string, int64) (int64, error)
+	IncrementBy(context.Context, string, int64, IncrOpts) (*Counter, error)
+	Get(context.Context, string) (int64, error)
+	IncrementMany(context.Context, map[string]int64) (map[string]int64, error)
+	GetMany(context.Context, []string) (map[string]int64, error)
+	Set(context.Context, string, int64) error
+	Delete(context.Context, string) error
+	// Count returns number of keys starting with prefix.
+	Count(context.Context, string) (int64, error)
+	// Subscribe streams changes of key until context is done.
+	Subscribe(context.Context, string) (<-chan *Event, error)
+	// Events returns recent changes of key with id greater than given one,
+	// oldest first.
+	Events(context.Context, string, int64) ([]*Event, error)
+	// History returns increments of key in [from, to) grouped by step.
+	History(ctx context.Context, key string, from, to time.Time, step time.Duration) ([]*Point, error)
+}
+
+// resolution picks bucket size that step can be built from. Buckets are not
+// recorded without their retention, so hour steps fall back to minute ones
+// and steps no recorded buckets can build are rejected.
+func (r Retention) resolution(from, to time.Time, step time.Duration) (time.Duration, error) {
+	if step <= 0 || step%time.Minute != 0 || !from.Before(to) || to.Sub(from)/step > MaxHistoryPoints {
+		return 0, ErrBadStep
+	}
+
+	switch {
+	case step%time.Hour == 0 && r.Hour > 0:
+		return time.Hour, nil
+	case r.Minute > 0:
+		return time.Minute, nil
+	}
+
+	return 0, fmt.Errorf("%w, history of its resolution is disabled", ErrBadStep)
+}
+
+// fillPoints groups bucket deltas keyed by unix seconds into points of step
+// aligned to from, range without increments gets zero delta.
+func fillPoints(buckets map[int64]int64, from, to time.Time, step time.Duration) []*Point {
+	from = from.Truncate(step)
+
+	points := make([]*Point, 0, to.Sub(from)/step+1)
+	for t := from; t.Before(to); t = t.Add(step) {
+		points = append(points, &Point{Time: t.UTC()})
+	}
+
+	for ts, delta := range buckets {
+		t := time.Unix(ts, 0)
+		if t.Before(from) || !t.Before(to) {
+			continue
+		}
+
+		points[t.Sub(from)/step].Delta += delta
+	}
+
+	return points
 }
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetention_Resolution(t *testing.T) {
+	from := time.Unix(0, 0)
+	to := from.Add(24 * time.Hour)
+
+	testCases := []struct {
+		name      string
+		retention Retention
+		step      time.Duration
+		res       time.Duration
+		err       error
+	}{
+		{name: "minute step", retention: DefaultRetention, step: 90 * time.Minute, res: time.Minute},
+		{name: "hour step", retention: DefaultRetention, step: 2 * time.Hour, res: time.Hour},
+		{name: "hour step without hours", retention: Retention{Minute: time.Hour}, step: time.Hour, res: time.Minute},
+		{name: "hour step without minutes", retention: Retention{Hour: time.Hour}, step: time.Hour, res: time.Hour},
+		{name: "minute step without minutes", retention: Retention{Hour: time.Hour}, step: 90 * time.Minute, err: ErrBadStep},
+		{name: "nothing recorded", retention: Retention{}, step: time.Hour, err: ErrBadStep},
+		{name: "not whole minutes", retention: DefaultRetention, step: 90 * time.Second, err: ErrBadStep},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.retention.resolution(from, to, tc.step)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.res, res)
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQuotaExceeded .
+	ErrQuotaExceeded = errors.New("tenant key quota exceeded")
+	// ErrNoTenant .
+	ErrNoTenant = errors.New("tenant is required")
+)
+
+// QuotaDB is implemented by storages shared by several service instances.
+// They track keys of a group themselves and check quota atomically with
+// writes creating keys.
+type QuotaDB interface {
+	// WithQuota returns storage tracking keys it writes in group, writes
+	// creating more than max keys of group fail with ErrQuotaExceeded. Zero max
+	// only tracks keys.
+	WithQuota(group string, max int64) DB
+}
+
+type tenantCtxKey struct{}
+
+// WithTenant .
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenant)
+}
+
+// TenantFromContext returns empty string for requests without tenant.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantCtxKey{}).(string)
+	return tenant
+}
+
+// TenantPrefix is prepended to every key of the tenant. Tenant is used as
+// redis hash tag so all its keys land in the same cluster slot.
+func TenantPrefix(tenant string) string {
+	if tenant == "" {
+		return ""
+	}
+
+	return "t:{" + tenant + "}:"
+}
+
+// TenantDB namespaces keys by tenant taken from context and enforces per
+// tenant key quotas. Storages implementing QuotaDB check quotas themselves,
+// other ones are local to the process and are checked under a lock.
+type TenantDB struct {
+	DB     DB
+	quotas map[string]int64
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+var _ DB = (*TenantDB)(nil)
+
+// NewTenantDB .
+func NewTenantDB(db DB, quotas map[string]int64) *TenantDB {
+	return &TenantDB{
+		DB:     db,
+		quotas: quotas,
+		locks:  make(map[string]*sync.Mutex),
+	}
+}
+
+// IncrementBy .
+func (t *TenantDB) IncrementBy(ctx context.Context, key string, val int64, opts IncrOpts) (*Counter, error) {
+	prefix := TenantPrefix(TenantFromContext(ctx))
+	var c *Counter
+
+	err := t.withQuota(ctx, []string{prefix + key}, func(db DB) (err error) {
+		c, err = db.IncrementBy(ctx, prefix+key, val, opts)
+		return err
+	})
+
+	return c, err
+}
+
+// IncrementMany .
+func (t *TenantDB) IncrementMany(ctx context.Context, vals map[string]int64) (map[string]int64, error) {
+	prefix := TenantPrefix(TenantFromContext(ctx))
+
+	pvals := make(map[string]int64, len(vals))
+	keys := make([]string, 0, len(vals))
+	for k, v := range vals {
+		pvals[prefix+k] = v
+		keys = append(keys, prefix+k)
+	}
+
+	var res map[string]int64
+	err := t.withQuota(ctx, keys, func(db DB) (err error) {
+		res, err = db.IncrementMany(ctx, pvals)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return trimKeys(prefix, res), nil
+}
+
+// Get .
+func (t *TenantDB) Get(ctx context.Context, key string) (int64, error) {
+	return t.DB.Get(ctx, TenantPrefix(TenantFromContext(ctx))+key)
+}
+
+// GetMany .
+func (t *TenantDB) GetMany(ctx context.Context, keys []string) (map[string]int64, error) {
+	prefix := TenantPrefix(TenantFromContext(ctx))
+
+	pkeys := make([]string, len(keys))
+	for i, k := range keys {
+		pkeys[i] = prefix + k
+	}
+
+	res, err := t.DB.GetMany(ctx, pkeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return trimKeys(prefix, res), nil
+}
+
+// Set .
+func (t *TenantDB) Set(ctx context.Context, key string, val int64) error {
+	prefix := TenantPrefix(TenantFromContext(ctx))
+
+	return t.withQuota(ctx, []string{prefix + key}, func(db DB) error {
+		return db.Set(ctx, prefix+key, val)
+	})
+}
+
+// Delete .
+func (t *TenantDB) Delete(ctx context.Context, key string) error {
+	tenant := TenantFromContext(ctx)
+	return t.quotaDB(tenant).Delete(ctx, TenantPrefix(tenant)+key)
+}
+
+// Count counts keys with prefix inside of tenant namespace. Without tenant it
+// would count every key, so it is rejected.
+func (t *TenantDB) Count(ctx context.Context, prefix string) (int64, error) {
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return 0, ErrNoTenant
+	}
+
+	return t.DB.Count(ctx, TenantPrefix(tenant)+prefix)
+}
+
+// quotaDB returns storage tracking keys of tenant when it can.
+func (t *TenantDB) quotaDB(tenant string) DB {
+	qdb, ok := t.DB.(QuotaDB)
+	if !ok || tenant == "" {
+		return t.DB
+	}
+
+	return qdb.WithQuota(TenantPrefix(tenant), t.quotas[tenant])
+}
+
+// withQuota runs fn with storage enforcing quota of tenant. Local storages
+// are counted under lock of the tenant before fn runs.
+func (t *TenantDB) withQuota(ctx context.Context, keys []string, fn func(DB) error) error {
+	tenant := TenantFromContext(ctx)
+
+	// quota of requests without tenant would count every key
+	quota := t.quotas[tenant]
+	if tenant == "" && quota > 0 {
+		return ErrNoTenant
+	}
+
+	if _, ok := t.DB.(QuotaDB); ok || quota <= 0 {
+		return fn(t.quotaDB(tenant))
+	}
+
+	lock := t.lock(tenant)
+	lock.Lock()
+	defer lock.Unlock()
+
+	existing, err := t.DB.GetMany(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	if created := int64(len(keys) - len(existing)); created > 0 {
+		n, err := t.DB.Count(ctx, TenantPrefix(tenant))
+		if err != nil {
+			return err
+		}
+
+		if n+created > quota {
+			return ErrQuotaExceeded
+		}
+	}
+
+	return fn(t.DB)
+}
+
+func (t *TenantDB) lock(tenant string) *sync.Mutex {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l, ok := t.locks[tenant]
+	if !ok {
+		l = &sync.Mutex{}
+		t.locks[tenant] = l
+	}
+
+	return l
+}
+
+func trimKeys(prefix string, m map[string]int64) map[string]int64 {
+	if prefix == "" {
+		return m
+	}
+
+	res := make(map[string]int64, len(m))
+	for k, v := range m {
+		res[strings.TrimPrefix(k, prefix)] = v
+	}
+
+	return res
+}
+
+// Subscribe .
+func (t *TenantDB) Subscribe(ctx context.Context, key string) (<-chan *Event, error) {
+	prefix := TenantPrefix(TenantFromContext(ctx))
+
+	evs, err := t.DB.Subscribe(ctx, prefix+key)
+	if err != nil || prefix == "" {
+		return evs, err
+	}
+
+	ch := make(chan *Event)
+	go func() {
+		defer close(ch)
+
+		for ev := range evs {
+			ev.Key = strings.TrimPrefix(ev.Key, prefix)
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Events .
+func (t *TenantDB) Events(ctx context.Context, key string, after int64) ([]*Event, error) {
+	prefix := TenantPrefix(TenantFromContext(ctx))
+
+	evs, err := t.DB.Events(ctx, prefix+key, after)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ev := range evs {
+		ev.Key = strings.TrimPrefix(ev.Key, prefix)
+	}
+
+	return evs, nil
+}
+
+// History .
+func (t *TenantDB) History(ctx context.Context, key string, from, to time.Time, step time.Duration) ([]*Point, error) {
+	return t.DB.History(ctx, TenantPrefix(TenantFromContext(ctx))+key, from, to, step)
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantDB_RedisQuota(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	// two service instances sharing redis
+	newDB := func() *TenantDB {
+		db := NewDB(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+		t.Cleanup(func() { db.Stop() })
+		return NewTenantDB(db, map[string]int64{"a": 2})
+	}
+	db1, db2 := newDB(), newDB()
+
+	ctx := WithTenant(context.Background(), "a")
+
+	_, err = db1.IncrementBy(ctx, "x", 1, IncrOpts{TTL: time.Second})
+	assert.NoError(t, err)
+	assert.NoError(t, db2.Set(ctx, "y", 1))
+
+	_, err = db2.IncrementBy(ctx, "z", 1, IncrOpts{})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = db1.IncrementMany(ctx, map[string]int64{"y": 1, "z": 1})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// nothing of rejected batch is applied
+	n, err := db1.Get(ctx, "y")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// existing keys are updated within quota
+	_, err = db2.IncrementBy(ctx, "x", 1, IncrOpts{})
+	assert.NoError(t, err)
+
+	// deleted key frees its slot
+	assert.NoError(t, db1.Delete(ctx, "y"))
+	_, err = db2.IncrementBy(ctx, "z", 1, IncrOpts{})
+	assert.NoError(t, err)
+
+	// so does expired one
+	_, err = db1.IncrementBy(ctx, "w", 1, IncrOpts{})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	redisServer.FastForward(2 * time.Second)
+
+	_, err = db1.IncrementBy(ctx, "w", 1, IncrOpts{})
+	assert.NoError(t, err)
+
+	// other tenants are not limited
+	other := WithTenant(context.Background(), "b")
+	_, err = db1.IncrementMany(other, map[string]int64{"x": 1, "y": 1, "z": 1})
+	assert.NoError(t, err)
+}
+
+func TestTenantDB_NoTenant(t *testing.T) {
+	mdb, err := NewMemoryDB("", 0)
+	assert.NoError(t, err)
+
+	db := NewTenantDB(mdb, map[string]int64{"": 1})
+
+	_, err = db.Count(context.Background(), "")
+	assert.ErrorIs(t, err, ErrNoTenant)
+
+	_, err = db.IncrementBy(context.Background(), "x", 1, IncrOpts{})
+	assert.ErrorIs(t, err, ErrNoTenant)
+}
@@ -0,0 +1,106 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"service1/apierr"
+	"service1/logger"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain of google.rpc.ErrorInfo details.
+const ErrorDomain = "service1"
+
+var grpcCodes = map[apierr.Code]codes.Code{
+	apierr.CodeMalformed:     codes.InvalidArgument,
+	apierr.CodeValidation:    codes.InvalidArgument,
+	apierr.CodeBadArgument:   codes.InvalidArgument,
+	apierr.CodeUnauthorized:  codes.Unauthenticated,
+	apierr.CodeQuotaExceeded: codes.ResourceExhausted,
+	apierr.CodeNotFound:      codes.NotFound,
+	apierr.CodeNotInteger:    codes.FailedPrecondition,
+	apierr.CodeOutOfBounds:   codes.FailedPrecondition,
+	apierr.CodeConflict:      codes.Aborted,
+	apierr.CodeRemote:        codes.Unavailable,
+	apierr.CodeUnavailable:   codes.Unavailable,
+	apierr.CodeInternal:      codes.Internal,
+}
+
+// Code returns grpc code of api error code.
+func Code(c apierr.Code) codes.Code {
+	if code, ok := grpcCodes[c]; ok {
+		return code
+	}
+
+	return codes.Internal
+}
+
+// statusError maps err like http handlers do. Code of api error is sent as
+// ErrorInfo reason, its extensions as metadata and invalid fields as
+// BadRequest violations. Errors after deadline or cancel of caller keep
+// their context code.
+func statusError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Err()
+	}
+
+	e := apierr.From(err)
+	if e.Status() >= http.StatusInternalServerError {
+		logger.FromContext(ctx).Error("request failed", "code", e.Code, "err", err)
+	}
+
+	msg := e.Detail
+	if msg == "" {
+		msg = strings.ToLower(http.StatusText(e.Status()))
+	}
+
+	info := &errdetails.ErrorInfo{Reason: string(e.Code), Domain: ErrorDomain}
+	for k, v := range e.Ext {
+		if info.Metadata == nil {
+			info.Metadata = make(map[string]string, len(e.Ext))
+		}
+		info.Metadata[k] = fmt.Sprint(v)
+	}
+
+	st := status.New(Code(e.Code), msg)
+
+	var withDetails *status.Status
+	if len(e.Fields) > 0 {
+		withDetails, err = st.WithDetails(info, &errdetails.BadRequest{FieldViolations: violations("", e.Fields)})
+	} else {
+		withDetails, err = st.WithDetails(info)
+	}
+	if err != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
+
+// violations flattens nested fields of api error to paths like pairs.0.a.
+func violations(prefix string, fields map[string]interface{}) []*errdetails.BadRequest_FieldViolation {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var vs []*errdetails.BadRequest_FieldViolation
+	for _, k := range keys {
+		path := prefix + k
+		switch v := fields[k].(type) {
+		case map[string]interface{}:
+			vs = append(vs, violations(path+".", v)...)
+		default:
+			vs = append(vs, &errdetails.BadRequest_FieldViolation{Field: path, Description: fmt.Sprint(v)})
+		}
+	}
+
+	return vs
+}
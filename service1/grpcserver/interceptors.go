@@ -0,0 +1,84 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"service1/apierr"
+	"service1/database"
+	"service1/logger"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys, the same as http headers.
+const (
+	// APIKeyMD .
+	APIKeyMD = "x-api-key"
+	// RequestIDMD .
+	RequestIDMD = "x-request-id"
+)
+
+// ErrUnauthorized .
+var ErrUnauthorized = errors.New("missing or unknown api key")
+
+// TenantAuth resolves tenant of the caller by api key metadata like http
+// TenantAuth does. Without configured keys all calls belong to the default
+// tenant.
+func TenantAuth(keys map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if len(keys) == 0 {
+			return handler(ctx, req)
+		}
+
+		tenant, ok := keys[first(ctx, APIKeyMD)]
+		if !ok {
+			return nil, statusError(ctx, apierr.New(apierr.CodeUnauthorized, ErrUnauthorized))
+		}
+
+		return handler(database.WithTenant(ctx, tenant), req)
+	}
+}
+
+// RequestLogger stores logger with request id and method in context and logs
+// served calls. Request id is taken from metadata when valid, or generated,
+// and returned in response header.
+func RequestLogger(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+
+	id := first(ctx, RequestIDMD)
+	if !logger.ValidRequestID(id) {
+		id = logger.NewRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDMD, id))
+
+	log := logger.FromContext(ctx).With(
+		slog.String("request_id", id),
+		slog.String("rpc", info.FullMethod),
+	)
+
+	ctx = logger.WithRequestID(ctx, id)
+	ctx = logger.WithContext(ctx, log)
+
+	res, err := handler(ctx, req)
+
+	log.Info("request served",
+		slog.String("code", status.Code(err).String()),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	return res, err
+}
+
+func first(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if vals := md.Get(strings.ToLower(key)); len(vals) > 0 {
+		return vals[0]
+	}
+
+	return ""
+}
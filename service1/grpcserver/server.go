@@ -0,0 +1,95 @@
+// Package grpcserver serves service1 api over grpc. It calls the same
+// services.Service as http handlers, validates requests with models and maps
+// errors like apierr does for http.
+package grpcserver
+
+import (
+	"context"
+	"service1/apierr"
+	"service1/models"
+	service1v1 "service1/proto/service1/v1"
+	"service1/services"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"google.golang.org/grpc"
+)
+
+// Server .
+type Server struct {
+	service1v1.UnimplementedAPIServiceServer
+
+	service services.Service
+}
+
+// NewServer .
+func NewServer(s services.Service) *Server {
+	return &Server{service: s}
+}
+
+// New returns grpc server with Server registered behind logging and tenant
+// auth interceptors.
+func New(s services.Service, keys map[string]string, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(RequestLogger, TenantAuth(keys)))
+
+	srv := grpc.NewServer(opts...)
+	service1v1.RegisterAPIServiceServer(srv, NewServer(s))
+	return srv
+}
+
+// IncrementBy .
+func (s *Server) IncrementBy(ctx context.Context, req *service1v1.IncrementByRequest) (*service1v1.IncrementByResponse, error) {
+	msg := &models.IncrMsgIn{
+		Key:         req.GetKey(),
+		Val:         req.GetVal(),
+		TTL:         req.GetTtl(),
+		TTLOnCreate: req.GetTtlOnCreate(),
+		Min:         req.Min,
+		Max:         req.Max,
+	}
+
+	if err := msg.Validate(); err != nil {
+		return nil, statusError(ctx, apierr.Invalid(err))
+	}
+
+	res, err := s.service.IncrementBy(ctx, msg)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return &service1v1.IncrementByResponse{Key: res.Key, Res: res.Res, Ttl: res.TTL}, nil
+}
+
+// HashString .
+func (s *Server) HashString(ctx context.Context, req *service1v1.HashStringRequest) (*service1v1.HashStringResponse, error) {
+	msg := &models.HashMsgIn{S: req.GetS(), Key: req.GetKey()}
+	if err := msg.Validate(); err != nil {
+		return nil, statusError(ctx, apierr.Invalid(err))
+	}
+
+	return &service1v1.HashStringResponse{Hmac: s.service.HashString(ctx, msg.S, msg.Key)}, nil
+}
+
+// MulStringVal .
+func (s *Server) MulStringVal(ctx context.Context, req *service1v1.MulStringValRequest) (*service1v1.MulStringValResponse, error) {
+	pairs := make([]*models.Pair, len(req.GetPairs()))
+	for i, p := range req.GetPairs() {
+		pairs[i] = &models.Pair{A: p.GetA(), B: p.GetB(), Key: p.GetKey()}
+	}
+
+	// field paths of errors start with request field like pairs.0.a
+	if err := validation.Validate(pairs, validation.Required); err != nil {
+		return nil, statusError(ctx, apierr.Invalid(validation.Errors{"pairs": err}))
+	}
+
+	res, err := s.service.MulStringVal(ctx, pairs)
+	if err != nil {
+		return nil, statusError(ctx, apierr.Remote(err))
+	}
+
+	out := &service1v1.MulStringValResponse{Products: make(map[string]int64, len(res))}
+	for k, v := range res {
+		out.Products[k] = int64(v)
+	}
+
+	return out, nil
+}
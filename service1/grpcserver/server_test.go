@@ -0,0 +1,240 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"service1/database"
+	service1v1 "service1/proto/service1/v1"
+	"service1/services"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T, s services.Service, keys map[string]string) service1v1.APIServiceClient {
+	lis := bufconn.Listen(1 << 20)
+
+	srv := New(s, keys)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return service1v1.NewAPIServiceClient(conn)
+}
+
+func newDB(t *testing.T) database.DB {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	t.Cleanup(redisServer.Close)
+
+	db := database.NewDB(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	t.Cleanup(func() { db.Stop() })
+
+	return db
+}
+
+// details returns reason of ErrorInfo and fields of BadRequest of err.
+func details(err error) (string, map[string]string, map[string]string) {
+	var reason string
+	meta, fields := map[string]string{}, map[string]string{}
+
+	for _, d := range status.Convert(err).Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			reason = d.Reason
+			for k, v := range d.Metadata {
+				meta[k] = v
+			}
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				fields[v.Field] = v.Description
+			}
+		}
+	}
+
+	return reason, meta, fields
+}
+
+func TestServer_IncrementBy(t *testing.T) {
+	client := newClient(t, services.NewTService(newDB(t), nil), nil)
+
+	min := int64(0)
+
+	testCases := []struct {
+		name   string
+		req    *service1v1.IncrementByRequest
+		res    *service1v1.IncrementByResponse
+		code   codes.Code
+		reason string
+		meta   map[string]string
+		fields map[string]string
+	}{
+		{
+			name: "ok",
+			req:  &service1v1.IncrementByRequest{Key: "x", Val: 2},
+			res:  &service1v1.IncrementByResponse{Key: "x", Res: 2, Ttl: -1},
+			code: codes.OK,
+		},
+		{
+			name:   "invalid",
+			req:    &service1v1.IncrementByRequest{Key: "x", TtlOnCreate: true},
+			code:   codes.InvalidArgument,
+			reason: "validation_failed",
+			meta:   map[string]string{},
+			fields: map[string]string{"val": "cannot be blank", "ttl_on_create": "can be set only with ttl"},
+		},
+		{
+			name:   "out of bounds",
+			req:    &service1v1.IncrementByRequest{Key: "x", Val: -3, Min: &min},
+			code:   codes.FailedPrecondition,
+			reason: "out_of_bounds",
+			meta:   map[string]string{"current": "2"},
+			fields: map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := client.IncrementBy(context.Background(), tc.req)
+			assert.Equal(t, tc.code, status.Code(err))
+
+			if tc.code == codes.OK {
+				assert.Equal(t, tc.res.String(), res.String())
+				return
+			}
+
+			reason, meta, fields := details(err)
+			assert.Equal(t, tc.reason, reason)
+			assert.Equal(t, tc.meta, meta)
+			assert.Equal(t, tc.fields, fields)
+		})
+	}
+}
+
+func TestServer_HashString(t *testing.T) {
+	client := newClient(t, services.NewTService(nil, nil), nil)
+
+	res, err := client.HashString(context.Background(), &service1v1.HashStringRequest{S: "test", Key: "test123"})
+	assert.NoError(t, err)
+	assert.Len(t, res.Hmac, 128)
+
+	_, err = client.HashString(context.Background(), &service1v1.HashStringRequest{S: "test"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, _, fields := details(err)
+	assert.Equal(t, map[string]string{"key": "cannot be blank"}, fields)
+}
+
+func TestServer_MulStringVal_Invalid(t *testing.T) {
+	client := newClient(t, services.NewTService(nil, nil), nil)
+
+	_, err := client.MulStringVal(context.Background(), &service1v1.MulStringValRequest{
+		Pairs: []*service1v1.Pair{{A: "1", B: "2", Key: "x"}, {B: "2", Key: "y"}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, _, fields := details(err)
+	assert.Equal(t, map[string]string{"pairs.1.a": "cannot be blank"}, fields)
+}
+
+func TestServer_MulStringVal_Deadline(t *testing.T) {
+	// remote server accepts connection but never answers
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer lis.Close()
+
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, c := range conns {
+				c.Close()
+			}
+		}()
+
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	client := newClient(t, services.NewTService(nil, services.NewTCPConnector(lis.Addr().String())), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.MulStringVal(ctx, &service1v1.MulStringValRequest{
+		Pairs: []*service1v1.Pair{{A: "1", B: "2", Key: "x"}},
+	})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestTenantAuth(t *testing.T) {
+	client := newClient(t, services.NewTService(newDB(t), nil), map[string]string{"key-a": "a"})
+
+	_, err := client.IncrementBy(context.Background(), &service1v1.IncrementByRequest{Key: "x", Val: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	reason, _, _ := details(err)
+	assert.Equal(t, "unauthorized", reason)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), APIKeyMD, "key-a")
+	res, err := client.IncrementBy(ctx, &service1v1.IncrementByRequest{Key: "x", Val: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), res.Res)
+}
+
+func TestRequestLogger(t *testing.T) {
+	client := newClient(t, services.NewTService(nil, nil), nil)
+
+	testCases := []struct {
+		name string
+		id   string
+		same bool
+	}{
+		{name: "accepted", id: "abc-123", same: true},
+		{name: "invalid replaced", id: "bad id!"},
+		{name: "generated"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.id != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, RequestIDMD, tc.id)
+			}
+
+			var hdr metadata.MD
+			_, err := client.HashString(ctx, &service1v1.HashStringRequest{S: "s", Key: "k"}, grpc.Header(&hdr))
+			assert.NoError(t, err)
+
+			ids := hdr.Get(RequestIDMD)
+			if assert.Len(t, ids, 1) {
+				assert.Equal(t, tc.same, ids[0] == tc.id)
+				assert.NotEmpty(t, ids[0])
+			}
+		})
+	}
+}
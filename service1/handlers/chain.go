@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"service1/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Access log formats.
+const (
+	AccessLogNone   = "none"
+	AccessLogJSON   = "json"
+	AccessLogApache = "apache"
+)
+
+var (
+	// ErrInternal is returned to client instead of recovered panic.
+	ErrInternal = errors.New("internal server error")
+	// ErrUnknownAccessLog .
+	ErrUnknownAccessLog = errors.New("unknown access log format")
+)
+
+// MiddlewareConfig enables optional middlewares, zero value enables none.
+type MiddlewareConfig struct {
+	// Recovery turns handler panics into 500 responses.
+	Recovery bool
+	// AccessLog is none, json or apache.
+	AccessLog string
+	// AccessLogOutput receives apache access log lines, json ones go to
+	// request logger.
+	AccessLogOutput io.Writer
+	// CORS is enabled when it has allowed origins.
+	CORS CORSConfig
+	// Gzip compresses responses for clients accepting it.
+	Gzip bool
+	// SecurityHeaders sets headers hardening browser clients.
+	SecurityHeaders bool
+}
+
+// CORSConfig .
+type CORSConfig struct {
+	// Origins allowed to call the api, "*" allows any.
+	Origins []string
+	Methods []string
+	Headers []string
+	MaxAge  time.Duration
+}
+
+// Chain returns route aware middlewares in order they should be used on
+// router. Tracing, request logger and metrics are always on, so access log and
+// recovery see request id and metrics see recovered panics.
+func Chain(cfg MiddlewareConfig, log *slog.Logger) ([]mux.MiddlewareFunc, error) {
+	chain := []mux.MiddlewareFunc{Tracing, RequestLogger(log)}
+
+	switch cfg.AccessLog {
+	case "", AccessLogNone:
+	case AccessLogJSON, AccessLogApache:
+		chain = append(chain, AccessLog(cfg.AccessLog, cfg.AccessLogOutput))
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownAccessLog, cfg.AccessLog)
+	}
+
+	chain = append(chain, Metrics)
+
+	if cfg.Recovery {
+		chain = append(chain, Recovery)
+	}
+	if cfg.SecurityHeaders {
+		chain = append(chain, SecurityHeaders)
+	}
+	if len(cfg.CORS.Origins) > 0 {
+		chain = append(chain, CORS(cfg.CORS))
+	}
+	if cfg.Gzip {
+		chain = append(chain, Gzip)
+	}
+
+	return chain, nil
+}
+
+// Recovery responds with 500 to a panicking handler and logs the panic with
+// stack. Response already started cant be replaced, it is cut off.
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+
+			// server aborts response silently on it
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			logger.FromContext(r.Context()).Error("handler panicked",
+				slog.Any("panic", v),
+				slog.String("stack", string(debug.Stack())),
+			)
+
+			if !rec.wrote {
+				respondError(rec, r, ErrInternal)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// AccessLog logs every served request. Json lines go to request logger,
+// apache ones are written to out in common log format.
+func AccessLog(format string, out io.Writer) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+			start := time.Now()
+
+			next.ServeHTTP(rec, r)
+
+			if format == AccessLogApache {
+				fmt.Fprintln(out, apacheLine(r, rec, start))
+				return
+			}
+
+			logger.FromContext(r.Context()).Info("request served",
+				slog.Int("code", rec.code),
+				slog.Int64("bytes", rec.size),
+				slog.Duration("duration", time.Since(start)),
+			)
+		})
+	}
+}
+
+func apacheLine(r *http.Request, rec *statusRecorder, start time.Time) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+
+	size := "-"
+	if rec.size > 0 {
+		size = strconv.FormatInt(rec.size, 10)
+	}
+
+	return fmt.Sprintf(`%s - - [%s] "%s %s %s" %d %s`,
+		host, start.Format("02/Jan/2006:15:04:05 -0700"), r.Method, uri, r.Proto, rec.code, size)
+}
+
+// CORS allows browser calls from configured origins and answers preflight
+// requests itself. Router needs an OPTIONS route for preflight to reach it.
+func CORS(cfg CORSConfig) mux.MiddlewareFunc {
+	methods := strings.Join(cfg.Methods, ", ")
+	headers := strings.Join(cfg.Headers, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	allowed := func(origin string) bool {
+		for _, o := range cfg.Origins {
+			if o == "*" || o == origin {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+
+			if origin == "" || !allowed(origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", RequestIDHeader)
+
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			if cfg.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// SecurityHeaders forbids content sniffing, framing and referrer leaking. The
+// api serves only json, so content security policy denies everything.
+func SecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		h.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+		if r.TLS != nil {
+			h.Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Gzip compresses responses of clients accepting gzip. Websocket upgrades and
+// event streams are passed as is.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.Close()
+
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// gzipWriter decides on the first write whether to compress, as content type
+// is known only then.
+type gzipWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	w.decide(code)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipWriter) Write(bs []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.gz == nil {
+		return w.ResponseWriter.Write(bs)
+	}
+
+	return w.gz.Write(bs)
+}
+
+func (w *gzipWriter) decide(code int) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	h := w.Header()
+	if code == http.StatusNoContent || code == http.StatusNotModified || h.Get("Content-Encoding") != "" ||
+		strings.HasPrefix(h.Get("Content-Type"), "text/event-stream") {
+		return
+	}
+
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+}
+
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *gzipWriter) Close() error {
+	if w.gz == nil {
+		return nil
+	}
+
+	return w.gz.Close()
+}
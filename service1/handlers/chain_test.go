@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"service1/apierr"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newChainRouter(t *testing.T, cfg MiddlewareConfig) *mux.Router {
+	chain, err := Chain(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.NoError(t, err)
+
+	r := mux.NewRouter()
+	r.Use(chain...)
+	r.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	r.HandleFunc("/counters/{key}", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, r, http.StatusOK, map[string]int64{mux.Vars(r)["key"]: 1})
+	}).Methods(http.MethodGet)
+	r.PathPrefix("/").Methods(http.MethodOptions).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	return r
+}
+
+func serve(r http.Handler, method, url string, header map[string]string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestChain_UnknownAccessLog(t *testing.T) {
+	_, err := Chain(MiddlewareConfig{AccessLog: "combined"}, slog.Default())
+	assert.ErrorIs(t, err, ErrUnknownAccessLog)
+}
+
+func TestRecovery(t *testing.T) {
+	r := newChainRouter(t, MiddlewareConfig{Recovery: true})
+
+	rec := serve(r, http.MethodGet, "/panic", nil)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, apierr.ContentType, rec.Header().Get("Content-Type"))
+
+	var p apierr.Problem
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	assert.Equal(t, apierr.CodeInternal, p.Code)
+	assert.Equal(t, "/panic", p.Instance)
+	assert.Empty(t, p.Detail)
+	assert.Equal(t, rec.Header().Get(RequestIDHeader), p.RequestID)
+
+	r = newChainRouter(t, MiddlewareConfig{})
+	assert.Panics(t, func() { serve(r, http.MethodGet, "/panic", nil) })
+}
+
+func TestAccessLog_Apache(t *testing.T) {
+	var buf bytes.Buffer
+	r := newChainRouter(t, MiddlewareConfig{AccessLog: AccessLogApache, AccessLogOutput: &buf})
+
+	serve(r, http.MethodGet, "/counters/a", nil)
+	assert.Regexp(t, `^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /counters/a HTTP/1\.1" 200 8\n$`, buf.String())
+}
+
+func TestCORS(t *testing.T) {
+	r := newChainRouter(t, MiddlewareConfig{CORS: CORSConfig{
+		Origins: []string{"https://app.example.com"},
+		Methods: []string{http.MethodGet, http.MethodPost},
+		Headers: []string{"Content-Type", APIKeyHeader},
+		MaxAge:  10 * time.Minute,
+	}})
+
+	testCases := []struct {
+		name    string
+		method  string
+		header  map[string]string
+		code    int
+		origin  string
+		methods string
+	}{
+		{
+			name:    "preflight",
+			method:  http.MethodOptions,
+			header:  map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST"},
+			code:    http.StatusNoContent,
+			origin:  "https://app.example.com",
+			methods: "GET, POST",
+		},
+		{
+			name:   "simple request",
+			method: http.MethodGet,
+			header: map[string]string{"Origin": "https://app.example.com"},
+			code:   http.StatusOK,
+			origin: "https://app.example.com",
+		},
+		{
+			name:   "unknown origin",
+			method: http.MethodGet,
+			header: map[string]string{"Origin": "https://evil.example.com"},
+			code:   http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(r, tc.method, "/counters/a", tc.header)
+
+			assert.Equal(t, tc.code, rec.Code)
+			assert.Equal(t, tc.origin, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tc.methods, rec.Header().Get("Access-Control-Allow-Methods"))
+		})
+	}
+}
+
+func TestGzip(t *testing.T) {
+	r := newChainRouter(t, MiddlewareConfig{Gzip: true})
+
+	rec := serve(r, http.MethodGet, "/counters/a", map[string]string{"Accept-Encoding": "gzip, deflate"})
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+
+	gz, err := gzip.NewReader(rec.Body)
+	assert.NoError(t, err)
+	bs, err := io.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`+"\n", string(bs))
+
+	rec = serve(r, http.MethodGet, "/counters/a", nil)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{"a":1}`+"\n", rec.Body.String())
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	rec := serve(newChainRouter(t, MiddlewareConfig{SecurityHeaders: true}), http.MethodGet, "/counters/a", nil)
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+
+	rec = serve(newChainRouter(t, MiddlewareConfig{}), http.MethodGet, "/counters/a", nil)
+	assert.Empty(t, rec.Header().Get("X-Content-Type-Options"))
+}
@@ -1,27 +1,98 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"service1/apierr"
+	"service1/database"
+	"service1/logger"
 	"service1/models"
 	"service1/services"
+	"strconv"
+	"sync"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/gorilla/mux"
 )
 
-// ErrNotCorrectMsg .
-var ErrNotCorrectMsg = errors.New("not correct msg")
+const sseKeepAlive = 15 * time.Second
+
+var (
+	// ErrNotCorrectMsg .
+	ErrNotCorrectMsg = errors.New("not correct msg")
+	// ErrStreamUnsupported .
+	ErrStreamUnsupported = errors.New("streaming unsupported")
+	// ErrKeyMismatch .
+	ErrKeyMismatch = errors.New("must be the same as key in path")
+)
 
 // Handler .
 type Handler struct {
 	service services.Service
+
+	// done is canceled by Shutdown, streams outliving requests end with it
+	done     context.Context
+	shutdown context.CancelFunc
+	mu       sync.Mutex
+	streams  sync.WaitGroup
 }
 
 // NewHandler .
 func NewHandler(s services.Service) *Handler {
-	return &Handler{service: s}
+	done, shutdown := context.WithCancel(context.Background())
+	return &Handler{service: s, done: done, shutdown: shutdown}
+}
+
+// Shutdown ends event streams, closes websockets gracefully and waits for
+// them until ctx is done. http.Server does not wait for hijacked connections
+// and waits for streams until its shutdown times out, so it is called next
+// to server Shutdown.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.shutdown()
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.streams.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// streamContext returns context of r which is canceled on Shutdown too.
+// Streams started after Shutdown end right away and are not waited for.
+func (h *Handler) streamContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(r.Context())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.done.Err() != nil {
+		cancel()
+		return ctx, cancel
+	}
+
+	h.streams.Add(1)
+	stop := context.AfterFunc(h.done, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+		h.streams.Done()
+	}
 }
 
 // IncrementByHandler .
@@ -29,24 +100,292 @@ func (h *Handler) IncrementByHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var msgin models.IncrMsgIn
 		if err := json.NewDecoder(r.Body).Decode(&msgin); err != nil {
-			respondError(w, r, http.StatusInternalServerError, err)
+			respondError(w, r, apierr.Malformed(err))
+			return
+		}
+
+		res, ok := h.incrementBy(w, r, &msgin)
+		if !ok {
+			return
+		}
+
+		respond(w, r, http.StatusOK, res)
+	}
+
+}
+
+// IncrementCounterHandler increments counter named in path. Body is the same
+// as of IncrementByHandler, its key may only repeat the path one.
+func (h *Handler) IncrementCounterHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var msgin models.IncrMsgIn
+		if err := json.NewDecoder(r.Body).Decode(&msgin); err != nil {
+			respondError(w, r, apierr.Malformed(err))
+			return
+		}
+
+		key := mux.Vars(r)["key"]
+		if msgin.Key != "" && msgin.Key != key {
+			respondError(w, r, apierr.Invalid(validation.Errors{"key": ErrKeyMismatch}))
+			return
+		}
+		msgin.Key = key
+
+		res, ok := h.incrementBy(w, r, &msgin)
+		if !ok {
+			return
+		}
+
+		respond(w, r, http.StatusOK, res)
+	}
+}
+
+// incrementBy validates msgin and increments counter, on failure it responds
+// with error itself.
+func (h *Handler) incrementBy(w http.ResponseWriter, r *http.Request, msgin *models.IncrMsgIn) (*models.IncrMsgOut, bool) {
+	if err := msgin.Validate(); err != nil {
+		respondError(w, r, apierr.Invalid(err))
+		return nil, false
+	}
+
+	res, err := h.service.IncrementBy(r.Context(), msgin)
+	if err != nil {
+		respondError(w, r, err)
+		return nil, false
+	}
+
+	return res, true
+}
+
+// IncrementManyHandler .
+func (h *Handler) IncrementManyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var batch models.IncrBatchIn
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			respondError(w, r, apierr.Malformed(err))
+			return
+		}
+
+		if err := batch.Validate(); err != nil {
+			respondError(w, r, apierr.Invalid(err))
+			return
+		}
+
+		res, err := h.service.IncrementMany(r.Context(), batch)
+		if err != nil {
+			respondError(w, r, err)
+			return
+		}
+
+		respond(w, r, http.StatusOK, res)
+	}
+}
+
+// GetCounterHandler .
+func (h *Handler) GetCounterHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := mux.Vars(r)["key"]
+		if err := models.ValidateKey(key); err != nil {
+			respondError(w, r, apierr.Invalid(err))
+			return
+		}
+
+		res, err := h.service.Get(r.Context(), key)
+		if err != nil {
+			respondError(w, r, err)
+			return
+		}
+
+		respond(w, r, http.StatusOK, res)
+	}
+}
+
+// SetCounterHandler .
+func (h *Handler) SetCounterHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := mux.Vars(r)["key"]
+		if err := models.ValidateKey(key); err != nil {
+			respondError(w, r, apierr.Invalid(err))
+			return
+		}
+
+		var msgin models.SetMsgIn
+		if err := json.NewDecoder(r.Body).Decode(&msgin); err != nil {
+			respondError(w, r, apierr.Malformed(err))
 			return
 		}
 
 		if err := msgin.Validate(); err != nil {
-			respondError(w, r, http.StatusBadRequest, err)
+			respondError(w, r, apierr.Invalid(err))
 			return
 		}
 
-		res, err := h.service.IncrementBy(r.Context(), msgin.Key, msgin.Val)
+		res, err := h.service.Set(r.Context(), key, *msgin.Val)
 		if err != nil {
-			respondError(w, r, http.StatusInternalServerError, err)
+			respondError(w, r, err)
 			return
 		}
 
 		respond(w, r, http.StatusOK, res)
 	}
+}
+
+// DeleteCounterHandler .
+func (h *Handler) DeleteCounterHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := mux.Vars(r)["key"]
+		if err := models.ValidateKey(key); err != nil {
+			respondError(w, r, apierr.Invalid(err))
+			return
+		}
+
+		if err := h.service.Delete(r.Context(), key); err != nil {
+			respondError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CounterEventsHandler streams counter changes as server-sent events.
+func (h *Handler) CounterEventsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := mux.Vars(r)["key"]
+		if err := models.ValidateKey(key); err != nil {
+			respondError(w, r, apierr.Invalid(err))
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			respondError(w, r, ErrStreamUnsupported)
+			return
+		}
 
+		var lastID int64
+		if s := r.Header.Get("Last-Event-ID"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				respondError(w, r, apierr.New(apierr.CodeBadArgument, ErrNotCorrectMsg))
+				return
+			}
+			lastID = id
+		}
+
+		ctx, cancel := h.streamContext(r)
+		defer cancel()
+
+		evs, err := h.service.Subscribe(ctx, key, lastID)
+		if err != nil {
+			respondError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepalive := time.NewTicker(sseKeepAlive)
+		defer keepalive.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-keepalive.C:
+				fmt.Fprint(w, ": keepalive\n\n")
+			case ev, ok := <-evs:
+				if !ok {
+					return
+				}
+
+				if err := writeEvent(w, ev); err != nil {
+					logger.FromContext(r.Context()).Warn("cant write event", "err", err)
+					return
+				}
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w io.Writer, ev *database.Event) error {
+	bs, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	// snapshot has no id so reconnect doesn't skip events after it
+	if ev.ID > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", ev.ID); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Op, bs)
+	return err
+}
+
+// CounterHistoryHandler .
+func (h *Handler) CounterHistoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseHistoryQuery(mux.Vars(r)["key"], r.URL.Query(), time.Now())
+		if err != nil {
+			respondError(w, r, apierr.New(apierr.CodeBadArgument, err))
+			return
+		}
+
+		if err := q.Validate(); err != nil {
+			respondError(w, r, apierr.Invalid(err))
+			return
+		}
+
+		res, err := h.service.History(r.Context(), q)
+		if err != nil {
+			respondError(w, r, err)
+			return
+		}
+
+		respond(w, r, http.StatusOK, res)
+	}
+}
+
+// parseHistoryQuery reads from and to as RFC 3339 or unix seconds and step as
+// duration. By default it is the last hour by minute.
+func parseHistoryQuery(key string, vals url.Values, now time.Time) (*models.HistoryQuery, error) {
+	q := &models.HistoryQuery{Key: key, From: now.Add(-time.Hour), To: now, Step: time.Minute}
+
+	for name, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		s := vals.Get(name)
+		if s == "" {
+			continue
+		}
+
+		if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+			*t = time.Unix(sec, 0)
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("%v: not correct time", name)
+		}
+		*t = parsed
+	}
+
+	if s := vals.Get("step"); s != "" {
+		step, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, errors.New("step: not correct duration")
+		}
+		q.Step = step
+	}
+
+	return q, nil
 }
 
 // HashStringHandler .
@@ -55,19 +394,23 @@ func (h *Handler) HashStringHandler() http.HandlerFunc {
 		var msgin *models.HashMsgIn
 
 		if err := json.NewDecoder(r.Body).Decode(&msgin); err != nil {
-			fmt.Println(err)
-			respondError(w, r, http.StatusInternalServerError, ErrNotCorrectMsg)
+			respondError(w, r, apierr.Malformed(err))
+			return
+		}
+
+		if msgin == nil {
+			respondError(w, r, apierr.Malformed(ErrNotCorrectMsg))
 			return
 		}
 
 		if err := msgin.Validate(); err != nil {
-			fmt.Println(err)
-			respondError(w, r, http.StatusBadRequest, ErrNotCorrectMsg)
+			respondError(w, r, apierr.Invalid(err))
 			return
 		}
 
 		res := h.service.HashString(r.Context(), msgin.S, msgin.Key)
 
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(res))
 	}
@@ -79,20 +422,18 @@ func (h *Handler) MulStringValHandler() http.HandlerFunc {
 
 		var pairs []*models.Pair
 		if err := json.NewDecoder(r.Body).Decode(&pairs); err != nil {
-			fmt.Println(err)
-			respondError(w, r, http.StatusInternalServerError, ErrNotCorrectMsg)
+			respondError(w, r, apierr.Malformed(err))
 			return
 		}
 
-		if err := validation.Validate(pairs); err != nil {
-			fmt.Println(err)
-			respondError(w, r, http.StatusBadRequest, ErrNotCorrectMsg)
+		if err := validation.Validate(pairs, validation.Each(validation.NotNil)); err != nil {
+			respondError(w, r, apierr.Invalid(err))
 			return
 		}
 
 		res, err := h.service.MulStringVal(r.Context(), pairs)
 		if err != nil {
-			respondError(w, r, http.StatusInternalServerError, err)
+			respondError(w, r, apierr.Remote(err))
 			return
 		}
 
@@ -100,14 +441,28 @@ func (h *Handler) MulStringValHandler() http.HandlerFunc {
 	}
 }
 
-func respondError(w http.ResponseWriter, r *http.Request, code int, err error) {
-	respond(w, r, code, map[string]string{"error": err.Error()})
+// respondError writes err as problem details, errors not mapped by handler
+// get their code from apierr.From.
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	e := apierr.From(err)
+	if e.Status() >= http.StatusInternalServerError {
+		logger.FromContext(r.Context()).Error("request failed", "code", e.Status(), "err", err)
+	}
+
+	p := e.Problem(r.URL.Path)
+	p.RequestID = logger.RequestID(r.Context())
+
+	w.Header().Set("Content-Type", apierr.ContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logger.FromContext(r.Context()).Warn("cant write response", "err", err)
+	}
 }
 
 func respond(w http.ResponseWriter, r *http.Request, code int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		fmt.Println(err)
+		logger.FromContext(r.Context()).Warn("cant write response", "err", err)
 	}
 }
@@ -3,19 +3,36 @@ package handlers
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
+	"io"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"service1/database"
+	"service1/logger"
+	"service1/metrics"
+	"service1/models"
 	"service1/services"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/alicebob/miniredis"
+	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -51,8 +68,7 @@ func TestHandlerHashStringHandler(t *testing.T) {
 					"key": "test123"
 				 }`,
 			res: func() string {
-				msg, _ := json.Marshal(map[string]string{"error": ErrNotCorrectMsg.Error()})
-				return string(msg) + "\n"
+				return `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request has invalid fields","instance":"/test2","code":"validation_failed","errors":{"s":"cannot be blank"}}` + "\n"
 			},
 			expectedCode: http.StatusBadRequest,
 		},
@@ -63,10 +79,9 @@ func TestHandlerHashStringHandler(t *testing.T) {
 					"key": "test123"
 				 }`,
 			res: func() string {
-				msg, _ := json.Marshal(map[string]string{"error": ErrNotCorrectMsg.Error()})
-				return string(msg) + "\n"
+				return `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request has fields of wrong type","instance":"/test2","code":"malformed_request","errors":{"s":"must be a string"}}` + "\n"
 			},
-			expectedCode: http.StatusInternalServerError,
+			expectedCode: http.StatusBadRequest,
 		},
 	}
 
@@ -252,10 +267,9 @@ func TestHandlerMulStringValHandler_IncorrectRequestMsg(t *testing.T) {
 			}
 			]`,
 			res: func() string {
-				msg, _ := json.Marshal(map[string]string{"error": ErrNotCorrectMsg.Error()})
-				return string(msg) + "\n"
+				return `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request has fields of wrong type","instance":"/test3","code":"malformed_request","errors":{"0":{"a":"must be a string"}}}` + "\n"
 			},
-			expectedCode: http.StatusInternalServerError,
+			expectedCode: http.StatusBadRequest,
 		},
 		{
 			name: "empty field",
@@ -266,8 +280,15 @@ func TestHandlerMulStringValHandler_IncorrectRequestMsg(t *testing.T) {
 			}
 			]`,
 			res: func() string {
-				msg, _ := json.Marshal(map[string]string{"error": ErrNotCorrectMsg.Error()})
-				return string(msg) + "\n"
+				return `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request has invalid fields","instance":"/test3","code":"validation_failed","errors":{"0":{"a":"cannot be blank"}}}` + "\n"
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "null pair",
+			req:  `[null]`,
+			res: func() string {
+				return `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request has invalid fields","instance":"/test3","code":"validation_failed","errors":{"0":"is required"}}` + "\n"
 			},
 			expectedCode: http.StatusBadRequest,
 		},
@@ -313,7 +334,7 @@ func TestHandlerMulStringValHandler_RemoteErr(t *testing.T) {
 			"key": "x"
 			}
 			]`,
-		expectedCode: http.StatusInternalServerError,
+		expectedCode: http.StatusBadGateway,
 	}
 
 	handler := NewHandler(services.NewTService(nil,
@@ -344,10 +365,58 @@ func TestHandlerIncrementByHandler(t *testing.T) {
 			name: "ok",
 			req:  `{"key": "test","val": 12}`,
 			res: func() string {
-				return `{"test":12}` + "\n"
+				return `{"key":"test","res":12,"ttl":-1}` + "\n"
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "with ttl",
+			req:  `{"key": "ttl","val": 3,"ttl": 1500}`,
+			res: func() string {
+				return `{"key":"ttl","res":3,"ttl":1500}` + "\n"
 			},
 			expectedCode: http.StatusOK,
 		},
+		{
+			name: "out of bounds",
+			req:  `{"key": "stock","val": -2,"min": 0,"max": 10}`,
+			res: func() string {
+				return `{"code":"out_of_bounds","current":0,"detail":"counter bound would be violated","instance":"/test1","status":409,"title":"Conflict","type":"about:blank"}` + "\n"
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name: "bounded increment",
+			req:  `{"key": "stock","val": 7,"min": 0,"max": 10}`,
+			res: func() string {
+				return `{"key":"stock","res":7,"ttl":-1}` + "\n"
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "bounded decrement",
+			req:  `{"key": "stock","val": -7,"min": 0}`,
+			res: func() string {
+				return `{"key":"stock","res":0,"ttl":-1}` + "\n"
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "max less than min",
+			req:  `{"key": "stock","val": 1,"min": 5,"max": 1}`,
+			res: func() string {
+				return `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request has invalid fields","instance":"/test1","code":"validation_failed","errors":{"max":"must not be less than min"}}` + "\n"
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "ttl on create without ttl",
+			req:  `{"key": "ttl","val": 3,"ttl_on_create": true}`,
+			res: func() string {
+				return `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request has invalid fields","instance":"/test1","code":"validation_failed","errors":{"ttl_on_create":"can be set only with ttl"}}` + "\n"
+			},
+			expectedCode: http.StatusBadRequest,
+		},
 	}
 
 	redisServer, err := miniredis.Run()
@@ -402,7 +471,7 @@ func TestHandlerIncrementByHandler_IncorrectReqMsg(t *testing.T) {
 		{
 			name:         "invalid msg field type",
 			req:          `{"key": "test","val": "12"}`,
-			expectedCode: http.StatusInternalServerError,
+			expectedCode: http.StatusBadRequest,
 		},
 	}
 
@@ -444,3 +513,609 @@ func TestHandlerIncrementByHandler_DBError(t *testing.T) {
 	result := rec.Result()
 	assert.Equal(t, expectedCode, result.StatusCode)
 }
+
+func TestHandlerIncrementByHandler_Unavailable(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:       redisServer.Addr(),
+		MaxRetries: -1,
+	})
+
+	db := database.NewDB(redisClient)
+	defer db.Stop()
+
+	handler := NewHandler(services.NewTService(db, nil))
+	health := NewHealth()
+	health.Register("redis", db.Ping)
+	ready := health.ReadinessHandler()
+
+	do := func(h http.HandlerFunc, method, url, msg string) int {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(msg))
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	redisServer.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, do(handler.IncrementByHandler(), http.MethodPost, "/test1", `{"key": "test","val": 12}`))
+	assert.Equal(t, http.StatusServiceUnavailable, do(ready, http.MethodGet, "/readyz", ""))
+	assert.Equal(t, http.StatusServiceUnavailable, do(handler.IncrementByHandler(), http.MethodPost, "/test1", `{"key": "test","val": 12}`))
+
+	// hmac does not need redis
+	assert.Equal(t, http.StatusOK, do(handler.HashStringHandler(), http.MethodPost, "/test2", `{"s": "test","key": "test123"}`))
+
+	assert.NoError(t, redisServer.Restart())
+
+	assert.Eventually(t, func() bool {
+		return do(ready, http.MethodGet, "/readyz", "") == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, do(handler.IncrementByHandler(), http.MethodPost, "/test1", `{"key": "test","val": 12}`))
+}
+
+func TestHandlerCounters(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisServer.Addr(),
+	})
+
+	db := database.NewDB(redisClient)
+	defer db.Stop()
+
+	handler := NewHandler(services.NewTService(db, nil))
+
+	r := mux.NewRouter()
+	r.HandleFunc("/counters/{key}", handler.GetCounterHandler()).Methods(http.MethodGet)
+	r.HandleFunc("/counters/{key}", handler.SetCounterHandler()).Methods(http.MethodPut)
+	r.HandleFunc("/counters/{key}", handler.DeleteCounterHandler()).Methods(http.MethodDelete)
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		req          string
+		res          string
+		expectedCode int
+	}{
+		{
+			name:         "get missing",
+			method:       http.MethodGet,
+			path:         "/counters/test",
+			res:          `{"type":"about:blank","title":"Not Found","status":404,"detail":"counter not found","instance":"/counters/test","code":"not_found"}` + "\n",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "set",
+			method:       http.MethodPut,
+			path:         "/counters/test",
+			req:          `{"val": 5}`,
+			res:          `{"test":5}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "set without val",
+			method:       http.MethodPut,
+			path:         "/counters/test",
+			req:          `{}`,
+			res:          `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request has invalid fields","instance":"/counters/test","code":"validation_failed","errors":{"val":"is required"}}` + "\n",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "get",
+			method:       http.MethodGet,
+			path:         "/counters/test",
+			res:          `{"test":5}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "get too long key",
+			method:       http.MethodGet,
+			path:         "/counters/test12345678901234567890",
+			res:          `{"type":"about:blank","title":"Bad Request","status":400,"detail":"the length must be between 1 and 20","instance":"/counters/test12345678901234567890","code":"validation_failed"}` + "\n",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "delete",
+			method:       http.MethodDelete,
+			path:         "/counters/test",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "delete missing",
+			method:       http.MethodDelete,
+			path:         "/counters/test",
+			res:          `{"type":"about:blank","title":"Not Found","status":404,"detail":"counter not found","instance":"/counters/test","code":"not_found"}` + "\n",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			b := &bytes.Buffer{}
+			b.WriteString(tc.req)
+
+			req, _ := http.NewRequest(tc.method, tc.path, b)
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(rec, req)
+
+			result := rec.Result()
+			assert.Equal(t, tc.expectedCode, result.StatusCode)
+			assert.Equal(t, tc.res, rec.Body.String())
+		})
+	}
+}
+
+func TestHandlerIncrementManyHandler(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisServer.Addr(),
+	})
+
+	db := database.NewDB(redisClient)
+	defer db.Stop()
+
+	assert.NoError(t, redisServer.Set("text", "oops"))
+
+	handler := NewHandler(services.NewTService(db, nil))
+
+	testCases := []struct {
+		name         string
+		req          string
+		res          string
+		expectedCode int
+	}{
+		{
+			name:         "ok",
+			req:          `[{"key": "x","val": 2},{"key": "y","val": -3}]`,
+			res:          `{"x":2,"y":-3}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "duplicate key",
+			req:          `[{"key": "x","val": 2},{"key": "x","val": 1}]`,
+			res:          `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request has invalid fields","instance":"/counters:batchIncrement","code":"validation_failed","errors":{"1":{"key":"duplicate key"}}}` + "\n",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "empty batch",
+			req:          `[]`,
+			res:          `{"type":"about:blank","title":"Bad Request","status":400,"detail":"cannot be blank","instance":"/counters:batchIncrement","code":"validation_failed"}` + "\n",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "not integer value",
+			req:          `[{"key": "x","val": 2},{"key": "text","val": 1}]`,
+			res:          `{"type":"about:blank","title":"Conflict","status":409,"detail":"key text: value is not an integer or out of range","instance":"/counters:batchIncrement","code":"not_integer"}` + "\n",
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "nothing applied after failure",
+			req:          `[{"key": "x","val": 1}]`,
+			res:          `{"x":3}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			b := &bytes.Buffer{}
+			b.WriteString(tc.req)
+
+			req, _ := http.NewRequest(http.MethodPost, "/counters:batchIncrement", b)
+			req.Header.Set("Content-Type", "application/json")
+
+			handler.IncrementManyHandler().ServeHTTP(rec, req)
+
+			result := rec.Result()
+			assert.Equal(t, tc.expectedCode, result.StatusCode)
+			assert.Equal(t, tc.res, rec.Body.String())
+		})
+	}
+}
+
+func TestTenantAuth(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisServer.Addr(),
+	})
+
+	db := database.NewDB(redisClient)
+	defer db.Stop()
+
+	tenantDB := database.NewTenantDB(db, map[string]int64{"a": 1})
+	handler := NewHandler(services.NewTService(tenantDB, nil))
+
+	r := mux.NewRouter()
+	r.Use(TenantAuth(map[string]string{"key-a": "a", "key-b": "b"}))
+	r.HandleFunc("/test1", handler.IncrementByHandler()).Methods(http.MethodPost)
+	r.HandleFunc("/counters/{key}", handler.GetCounterHandler()).Methods(http.MethodGet)
+
+	testCases := []struct {
+		name         string
+		apiKey       string
+		method       string
+		path         string
+		req          string
+		res          string
+		expectedCode int
+	}{
+		{
+			name:         "no api key",
+			method:       http.MethodPost,
+			path:         "/test1",
+			req:          `{"key": "x","val": 1}`,
+			res:          `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"missing or unknown api key","instance":"/test1","code":"unauthorized"}` + "\n",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "tenant a increments",
+			apiKey:       "key-a",
+			method:       http.MethodPost,
+			path:         "/test1",
+			req:          `{"key": "x","val": 1}`,
+			res:          `{"key":"x","res":1,"ttl":-1}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "tenant b does not see counter of a",
+			apiKey:       "key-b",
+			method:       http.MethodGet,
+			path:         "/counters/x",
+			res:          `{"type":"about:blank","title":"Not Found","status":404,"detail":"counter not found","instance":"/counters/x","code":"not_found"}` + "\n",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "tenant a updates existing key within quota",
+			apiKey:       "key-a",
+			method:       http.MethodPost,
+			path:         "/test1",
+			req:          `{"key": "x","val": 1}`,
+			res:          `{"key":"x","res":2,"ttl":-1}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "tenant a exceeds quota",
+			apiKey:       "key-a",
+			method:       http.MethodPost,
+			path:         "/test1",
+			req:          `{"key": "y","val": 1}`,
+			res:          `{"type":"about:blank","title":"Forbidden","status":403,"detail":"tenant key quota exceeded","instance":"/test1","code":"quota_exceeded"}` + "\n",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "tenant b without quota",
+			apiKey:       "key-b",
+			method:       http.MethodPost,
+			path:         "/test1",
+			req:          `{"key": "y","val": 5}`,
+			res:          `{"key":"y","res":5,"ttl":-1}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			b := &bytes.Buffer{}
+			b.WriteString(tc.req)
+
+			req, _ := http.NewRequest(tc.method, tc.path, b)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(APIKeyHeader, tc.apiKey)
+
+			r.ServeHTTP(rec, req)
+
+			result := rec.Result()
+			assert.Equal(t, tc.expectedCode, result.StatusCode)
+			assert.Equal(t, tc.res, rec.Body.String())
+		})
+	}
+
+	assert.True(t, redisServer.Exists("t:{a}:x"))
+	assert.True(t, redisServer.Exists("t:{b}:y"))
+}
+
+func TestHandlerCounterEventsHandler(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisServer.Addr(),
+	})
+
+	db := database.NewDB(redisClient)
+	defer db.Stop()
+
+	serv := services.NewTService(db, nil)
+	handler := NewHandler(serv)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/counters/{key}/events", handler.CounterEventsHandler()).Methods(http.MethodGet)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ctx := context.Background()
+	_, err = serv.Set(ctx, "x", 5)
+	assert.NoError(t, err)
+
+	// readEvent returns id and data lines of next event
+	readEvent := func(reader *bufio.Reader) (string, string) {
+		var id, data string
+		for {
+			line, err := reader.ReadString('\n')
+			assert.NoError(t, err)
+
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				return id, data
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+
+	subscribe := func(lastID string) (*http.Response, *bufio.Reader) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/counters/x/events", nil)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+		return res, bufio.NewReader(res.Body)
+	}
+
+	res, reader := subscribe("")
+
+	id, data := readEvent(reader)
+	assert.Equal(t, "", id)
+	assert.Equal(t, `{"id":0,"op":"snapshot","key":"x","val":5}`, data)
+
+	_, err = serv.IncrementBy(ctx, &models.IncrMsgIn{Key: "x", Val: 2})
+	assert.NoError(t, err)
+
+	lastID, data := readEvent(reader)
+	assert.NotEmpty(t, lastID)
+	assert.Equal(t, `{"id":`+lastID+`,"op":"incr","key":"x","val":7,"delta":2}`, data)
+	res.Body.Close()
+
+	_, err = serv.IncrementBy(ctx, &models.IncrMsgIn{Key: "x", Val: 1})
+	assert.NoError(t, err)
+
+	res, reader = subscribe(lastID)
+	defer res.Body.Close()
+
+	id, data = readEvent(reader)
+	assert.NotEqual(t, lastID, id)
+	assert.Equal(t, `{"id":`+id+`,"op":"incr","key":"x","val":8,"delta":1}`, data)
+
+	// shutdown ends stream without waiting for client
+	sctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	assert.NoError(t, handler.Shutdown(sctx))
+
+	_, err = io.ReadAll(reader)
+	assert.NoError(t, err)
+}
+
+func TestHandlerCounterHistoryHandler(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisServer.Addr(),
+	})
+
+	db := database.NewDB(redisClient)
+	defer db.Stop()
+
+	serv := services.NewTService(db, nil)
+	handler := NewHandler(serv)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/counters/{key}/history", handler.CounterHistoryHandler()).Methods(http.MethodGet)
+
+	for _, v := range []int64{3, 2} {
+		_, err = serv.IncrementBy(context.Background(), &models.IncrMsgIn{Key: "x", Val: v})
+		assert.NoError(t, err)
+	}
+
+	now := time.Now()
+	from, to := strconv.FormatInt(now.Add(-2*time.Hour).Unix(), 10), strconv.FormatInt(now.Add(time.Hour).Unix(), 10)
+
+	testCases := []struct {
+		name         string
+		query        string
+		points       int
+		total        int64
+		expectedCode int
+	}{
+		{
+			name:         "by minute",
+			query:        "?from=" + from + "&to=" + to + "&step=1m",
+			points:       180,
+			total:        5,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "by hour",
+			query:        "?from=" + from + "&to=" + to + "&step=1h",
+			points:       3,
+			total:        5,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "default range",
+			points:       60,
+			total:        5,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "step too small",
+			query:        "?step=30s",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "to before from",
+			query:        "?from=" + to + "&to=" + from,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/counters/x/history"+tc.query, nil)
+
+			r.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			var res models.HistoryMsgOut
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+			assert.Equal(t, "x", res.Key)
+
+			var total int64
+			for _, p := range res.Points {
+				total += p.Delta
+			}
+
+			assert.InDelta(t, tc.points, len(res.Points), 1)
+			assert.Equal(t, tc.total, total)
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(Metrics)
+	r.HandleFunc("/counters/{key}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	requests := metrics.HTTPRequests.WithLabelValues("/counters/{key}", http.MethodGet, "404")
+	before := testutil.ToFloat64(requests)
+
+	for _, key := range []string{"a", "b"} {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/counters/"+key, nil)
+		r.ServeHTTP(rec, req)
+	}
+
+	assert.Equal(t, before+2, testutil.ToFloat64(requests))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.HTTPInFlight.WithLabelValues("/counters/{key}")))
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	r := mux.NewRouter()
+	r.Use(RequestLogger(log), AccessLog(AccessLogJSON, nil))
+	r.HandleFunc("/counters/{key}", func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, logger.RequestID(r.Context()))
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/counters/a", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	r.ServeHTTP(rec, req)
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, "request served", m["msg"])
+	assert.Equal(t, "/counters/{key}", m["route"])
+	assert.Equal(t, "10.0.0.1:1234", m["remote_addr"])
+	assert.Equal(t, float64(http.StatusTeapot), m["code"])
+	assert.NotEmpty(t, m["request_id"])
+	assert.Contains(t, m, "duration")
+}
+
+func TestRequestLogger_RequestID(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "missing", header: "", keep: false},
+		{name: "valid", header: "abc-123", keep: true},
+		{name: "line break", header: "abc\r\n12,43", keep: false},
+	}
+
+	r := mux.NewRouter()
+	r.Use(RequestLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(logger.RequestID(r.Context())))
+	})
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, tc.header)
+			r.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			assert.NotEmpty(t, id)
+			assert.Equal(t, id, rec.Body.String())
+			assert.Equal(t, tc.keep, id == tc.header)
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	r := mux.NewRouter()
+	r.Use(Tracing)
+	r.HandleFunc("/counters/{key}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/counters/a", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(rec, req)
+
+	spans := exp.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET /counters/{key}", spans[0].Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const checkTimeout = 2 * time.Second
+
+// Check reports whether a dependency is usable.
+type Check func(context.Context) error
+
+// CheckResult .
+type CheckResult struct {
+	Name    string  `json:"name"`
+	Status  string  `json:"status"`
+	Latency float64 `json:"latency_ms"`
+	Error   string  `json:"error,omitempty"`
+	// LastError is kept after dependency recovers to help debug flapping.
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// HealthMsgOut .
+type HealthMsgOut struct {
+	Status string         `json:"status"`
+	Checks []*CheckResult `json:"checks,omitempty"`
+}
+
+// Health is a registry of dependency checks run by readiness endpoint.
+type Health struct {
+	mu     sync.Mutex
+	checks map[string]Check
+	last   map[string]*CheckResult
+}
+
+// NewHealth .
+func NewHealth() *Health {
+	return &Health{
+		checks: make(map[string]Check),
+		last:   make(map[string]*CheckResult),
+	}
+}
+
+// Register adds check under name, check registered with the same name is
+// replaced.
+func (hl *Health) Register(name string, check Check) {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	hl.checks[name] = check
+}
+
+// LivenessHandler reports that process is alive and serves requests, it does
+// not depend on any check.
+func (hl *Health) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respond(w, r, http.StatusOK, &HealthMsgOut{Status: "ok"})
+	}
+}
+
+// ReadinessHandler runs all checks concurrently and reports 503 if any of
+// them fails, so load balancer stops routing requests to the instance.
+func (hl *Health) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		out := &HealthMsgOut{Status: "ready", Checks: hl.run(r.Context())}
+
+		code := http.StatusOK
+		for _, c := range out.Checks {
+			if c.Error != "" {
+				out.Status = "not ready"
+				code = http.StatusServiceUnavailable
+			}
+		}
+
+		respond(w, r, code, out)
+	}
+}
+
+func (hl *Health) run(ctx context.Context) []*CheckResult {
+	hl.mu.Lock()
+	checks := make(map[string]Check, len(hl.checks))
+	for name, check := range hl.checks {
+		checks[name] = check
+	}
+	hl.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	res := make([]*CheckResult, 0, len(checks))
+	ch := make(chan *CheckResult, len(checks))
+
+	for name, check := range checks {
+		go func(name string, check Check) {
+			start := time.Now()
+			err := check(ctx)
+
+			cr := &CheckResult{
+				Name:    name,
+				Status:  "ok",
+				Latency: float64(time.Since(start).Microseconds()) / 1000,
+			}
+
+			if err != nil {
+				now := time.Now().UTC()
+				cr.Status, cr.Error = "fail", err.Error()
+				cr.LastError, cr.LastErrorAt = cr.Error, &now
+			}
+
+			ch <- cr
+		}(name, check)
+	}
+
+	for range checks {
+		res = append(res, hl.remember(<-ch))
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// remember fills last error of successful check from previous runs.
+func (hl *Health) remember(cr *CheckResult) *CheckResult {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	if prev, ok := hl.last[cr.Name]; ok && cr.Error == "" {
+		cr.LastError, cr.LastErrorAt = prev.LastError, prev.LastErrorAt
+	}
+
+	hl.last[cr.Name] = cr
+	return cr
+}
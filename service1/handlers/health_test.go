@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	var redisErr error
+
+	health := NewHealth()
+	health.Register("redis", func(context.Context) error { return redisErr })
+	health.Register("service2", func(context.Context) error { return nil })
+
+	get := func(h http.HandlerFunc) (int, *HealthMsgOut) {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		h.ServeHTTP(rec, req)
+
+		var out HealthMsgOut
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+		return rec.Code, &out
+	}
+
+	code, out := get(health.LivenessHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", out.Status)
+
+	code, out = get(health.ReadinessHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", out.Status)
+	assert.Len(t, out.Checks, 2)
+
+	redisErr = errors.New("connection refused")
+
+	code, out = get(health.ReadinessHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not ready", out.Status)
+	assert.Equal(t, "redis", out.Checks[0].Name)
+	assert.Equal(t, "fail", out.Checks[0].Status)
+	assert.Equal(t, "connection refused", out.Checks[0].Error)
+	assert.Equal(t, "ok", out.Checks[1].Status)
+
+	// liveness does not depend on checks
+	code, _ = get(health.LivenessHandler())
+	assert.Equal(t, http.StatusOK, code)
+
+	redisErr = nil
+
+	code, out = get(health.ReadinessHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", out.Checks[0].Status)
+	assert.Empty(t, out.Checks[0].Error)
+	assert.Equal(t, "connection refused", out.Checks[0].LastError)
+	assert.NotNil(t, out.Checks[0].LastErrorAt)
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"service1/apierr"
+	"service1/database"
+	"service1/logger"
+	"service1/metrics"
+	"service1/tracing"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Request headers.
+const (
+	// APIKeyHeader .
+	APIKeyHeader = "X-API-Key"
+	// RequestIDHeader .
+	RequestIDHeader = "X-Request-ID"
+)
+
+// Websocket subprotocols. Browsers cant set headers of websocket handshake,
+// so they offer api key as subprotocol along with WSProtocol, e.g.
+// new WebSocket(url, ["counters", "api-key." + key]).
+const (
+	// WSProtocol is selected by server when offered.
+	WSProtocol = "counters"
+	// WSKeyProtocolPrefix prefixes api key offered as subprotocol.
+	WSKeyProtocolPrefix = "api-key."
+)
+
+// ErrUnauthorized .
+var ErrUnauthorized = errors.New("missing or unknown api key")
+
+// TenantAuth resolves tenant of the caller by api key and stores it in request
+// context for database layer. Without configured keys all requests belong to
+// the default tenant.
+func TenantAuth(keys map[string]string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(keys) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tenant, ok := keys[apiKey(r)]
+			if !ok {
+				respondError(w, r, apierr.New(apierr.CodeUnauthorized, ErrUnauthorized))
+				return
+			}
+
+			ctx := database.WithTenant(r.Context(), tenant)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// apiKey returns key of header, websocket handshakes may pass it as
+// subprotocol instead.
+func apiKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" || !websocket.IsWebSocketUpgrade(r) {
+		return key
+	}
+
+	for _, p := range websocket.Subprotocols(r) {
+		if strings.HasPrefix(p, WSKeyProtocolPrefix) {
+			return strings.TrimPrefix(p, WSKeyProtocolPrefix)
+		}
+	}
+
+	return ""
+}
+
+// RequestLogger stores logger with request id, route and remote address in
+// request context. Request id is taken from X-Request-ID header when valid, or
+// generated, and returned in response header.
+func RequestLogger(base *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !logger.ValidRequestID(id) {
+				id = logger.NewRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			log := base.With(
+				slog.String("request_id", id),
+				slog.String("route", routeTemplate(r)),
+				slog.String("method", r.Method),
+				slog.String("remote_addr", r.RemoteAddr),
+			)
+
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				log = log.With(slog.String("trace_id", sc.TraceID().String()))
+			}
+
+			ctx := logger.WithRequestID(r.Context(), id)
+			ctx = logger.WithContext(ctx, log)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Tracing starts server span of request named by route template. Trace of
+// incoming traceparent header is continued.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+			),
+		)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.code))
+		if rec.code >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.code))
+		}
+	})
+}
+
+// routeTemplate returns path template of matched route, so it does not depend
+// on counter keys, or path when no route matched.
+func routeTemplate(r *http.Request) string {
+	if cur := mux.CurrentRoute(r); cur != nil {
+		if tpl, err := cur.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+
+	return r.URL.Path
+}
+
+// Metrics counts requests and observes their latency per route template, so
+// label cardinality does not grow with counter keys.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+
+		inflight := metrics.HTTPInFlight.WithLabelValues(route)
+		inflight.Inc()
+		defer inflight.Dec()
+
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(rec, r)
+
+		metrics.HTTPDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		metrics.HTTPRequests.WithLabelValues(route, r.Method, strconv.Itoa(rec.code)).Inc()
+	})
+}
+
+// statusRecorder remembers response code and size. It keeps Flusher and
+// Hijacker of the wrapped writer for event streams and websockets.
+type statusRecorder struct {
+	http.ResponseWriter
+	code  int
+	size  int64
+	wrote bool
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	if !rec.wrote {
+		rec.code, rec.wrote = code, true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Write(bs []byte) (int, error) {
+	rec.wrote = true
+	n, err := rec.ResponseWriter.Write(bs)
+	rec.size += int64(n)
+	return n, err
+}
+
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	rec.code, rec.wrote = http.StatusSwitchingProtocols, true
+	return h.Hijack()
+}
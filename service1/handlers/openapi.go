@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"service1/apierr"
+	"service1/models"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+)
+
+// OpenAPIPath serves api document.
+const OpenAPIPath = "/openapi.json"
+
+const (
+	jsonType   = "application/json"
+	textType   = "text/plain"
+	eventsType = "text/event-stream"
+)
+
+var (
+	specOnce sync.Once
+	spec     *openapi3.T
+	specErr  error
+)
+
+// OpenAPI returns OpenAPI 3 document of every route served by NewRouter.
+// Schemas are generated from models and constrained by their limits.
+func OpenAPI() (*openapi3.T, error) {
+	specOnce.Do(func() {
+		spec, specErr = buildSpec()
+	})
+
+	return spec, specErr
+}
+
+// OpenAPIHandler .
+func OpenAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc, err := OpenAPI()
+		if err != nil {
+			respondError(w, r, err)
+			return
+		}
+
+		respond(w, r, http.StatusOK, doc)
+	}
+}
+
+func buildSpec() (*openapi3.T, error) {
+	schemas, err := modelSchemas()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:   "service1",
+			Version: "1.0.0",
+			Description: "Counters, hmac and multiplication of number pairs by remote server. " +
+				"Errors are RFC 7807 problem details.",
+		},
+		Servers: openapi3.Servers{{URL: "/"}},
+		Components: &openapi3.Components{
+			Schemas: schemas,
+			SecuritySchemes: openapi3.SecuritySchemes{
+				"apiKey": &openapi3.SecuritySchemeRef{Value: openapi3.NewSecurityScheme().
+					WithType("apiKey").WithIn("header").WithName(APIKeyHeader)},
+			},
+		},
+		Paths: openapi3.NewPaths(),
+	}
+
+	doc.Paths.Set("/", &openapi3.PathItem{Get: &openapi3.Operation{
+		OperationID: "hello",
+		Responses:   responses(http.StatusOK, textResponse("hello")),
+	}})
+	doc.Paths.Set("/healthz", &openapi3.PathItem{Get: &openapi3.Operation{
+		OperationID: "liveness",
+		Responses:   responses(http.StatusOK, jsonResponse("process is alive", "HealthMsgOut")),
+	}})
+	doc.Paths.Set("/readyz", &openapi3.PathItem{Get: &openapi3.Operation{
+		OperationID: "readiness",
+		Responses: responses(
+			http.StatusOK, jsonResponse("all dependencies are usable", "HealthMsgOut"),
+			http.StatusServiceUnavailable, jsonResponse("some dependency is not usable", "HealthMsgOut"),
+		),
+	}})
+	doc.Paths.Set("/metrics", &openapi3.PathItem{Get: &openapi3.Operation{
+		OperationID: "metrics",
+		Responses:   responses(http.StatusOK, textResponse("prometheus metrics")),
+	}})
+	doc.Paths.Set(OpenAPIPath, &openapi3.PathItem{Get: &openapi3.Operation{
+		OperationID: "openapi",
+		Responses:   responses(http.StatusOK, openapi3.NewResponse().WithDescription("this document").WithJSONSchema(openapi3.NewObjectSchema())),
+	}})
+
+	ops := apiOperations()
+	for _, rt := range (&Handler{}).routes() {
+		op := ops[rt.method+" "+rt.path]
+		if rt.legacy != "" {
+			legacy := *op
+			legacy.OperationID += "Legacy"
+			legacy.Deprecated = true
+			addOperation(doc, rt.legacy, rt.method, &legacy)
+		}
+
+		addOperation(doc, APIPrefix+rt.path, rt.method, op)
+	}
+
+	addOperation(doc, "/test1", http.MethodPost, &openapi3.Operation{
+		OperationID: "incrementByLegacy",
+		Summary:     "Increment counter named in body",
+		Deprecated:  true,
+		RequestBody: jsonBody("IncrMsgIn"),
+		Responses: withErrors(responses(http.StatusOK, jsonResponse("counter after increment", "IncrMsgOut")),
+			http.StatusBadRequest, http.StatusConflict),
+	})
+
+	// loading resolves refs between schemas
+	bs, err := doc.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err = openapi3.NewLoader().LoadFromData(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, doc.Validate(context.Background())
+}
+
+func addOperation(doc *openapi3.T, path, method string, op *openapi3.Operation) {
+	if op.Security == nil {
+		op.Security = &openapi3.SecurityRequirements{{}, {"apiKey": []string{}}}
+	}
+
+	var params openapi3.Parameters
+	for _, name := range pathVars(path) {
+		params = append(params, &openapi3.ParameterRef{Value: keyParam(name)})
+	}
+	op.Parameters = append(params, op.Parameters...)
+
+	item := doc.Paths.Value(path)
+	if item == nil {
+		item = &openapi3.PathItem{}
+		doc.Paths.Set(path, item)
+	}
+	item.SetOperation(method, op)
+}
+
+func pathVars(path string) []string {
+	var names []string
+	for _, part := range strings.Split(path, "{")[1:] {
+		names = append(names, part[:strings.IndexByte(part, '}')])
+	}
+
+	return names
+}
+
+func keyParam(name string) *openapi3.Parameter {
+	s := openapi3.NewStringSchema().WithMinLength(models.MinKeyLen).WithMaxLength(models.MaxKeyLen)
+	return openapi3.NewPathParameter(name).WithSchema(s)
+}
+
+// apiOperations describes v1 routes by method and path.
+func apiOperations() map[string]*openapi3.Operation {
+	counterErrors := []int{http.StatusNotFound, http.StatusConflict}
+
+	return map[string]*openapi3.Operation{
+		http.MethodPost + " /counters/{key}:increment": {
+			OperationID: "incrementCounter",
+			Summary:     "Increment counter by val, optionally within bounds and with ttl",
+			RequestBody: jsonBody("IncrementIn"),
+			Responses: withErrors(responses(http.StatusOK, jsonResponse("counter after increment", "IncrMsgOut")),
+				http.StatusBadRequest, http.StatusConflict),
+		},
+		http.MethodPost + " /counters:batchIncrement": {
+			OperationID: "incrementCounters",
+			Summary:     "Increment several counters atomically",
+			RequestBody: jsonBody("IncrBatchIn"),
+			Responses: withErrors(responses(http.StatusOK, jsonResponse("counters after increment", "CounterValues")),
+				http.StatusBadRequest, http.StatusConflict),
+		},
+		http.MethodGet + " /counters/{key}": {
+			OperationID: "getCounter",
+			Responses: withErrors(responses(http.StatusOK, jsonResponse("counter value by key", "CounterValues")),
+				http.StatusBadRequest, http.StatusNotFound, http.StatusConflict),
+		},
+		http.MethodPut + " /counters/{key}": {
+			OperationID: "setCounter",
+			RequestBody: jsonBody("SetMsgIn"),
+			Responses: withErrors(responses(http.StatusOK, jsonResponse("counter value by key", "CounterValues")),
+				http.StatusBadRequest),
+		},
+		http.MethodDelete + " /counters/{key}": {
+			OperationID: "deleteCounter",
+			Responses: withErrors(responses(http.StatusNoContent, openapi3.NewResponse().WithDescription("counter deleted")),
+				append([]int{http.StatusBadRequest}, counterErrors...)...),
+		},
+		http.MethodGet + " /counters/{key}/history": {
+			OperationID: "counterHistory",
+			Summary:     "Increments of counter summed by step",
+			Parameters: openapi3.Parameters{
+				{Value: openapi3.NewQueryParameter("from").WithSchema(openapi3.NewStringSchema()).
+					WithDescription("RFC 3339 time or unix seconds, an hour ago by default")},
+				{Value: openapi3.NewQueryParameter("to").WithSchema(openapi3.NewStringSchema()).
+					WithDescription("RFC 3339 time or unix seconds, now by default")},
+				{Value: openapi3.NewQueryParameter("step").WithSchema(openapi3.NewStringSchema()).
+					WithDescription("go duration of at least " + models.MinHistoryStep.String() + ", minute by default")},
+			},
+			Responses: withErrors(responses(http.StatusOK, jsonResponse("history points", "HistoryMsgOut")),
+				http.StatusBadRequest),
+		},
+		http.MethodGet + " /counters/{key}/events": {
+			OperationID: "counterEvents",
+			Summary:     "Stream of counter changes as server-sent events",
+			Parameters: openapi3.Parameters{
+				{Value: openapi3.NewHeaderParameter("Last-Event-ID").WithSchema(openapi3.NewInt64Schema())},
+			},
+			Responses: withErrors(responses(http.StatusOK, openapi3.NewResponse().WithDescription("events of counter").
+				WithContent(openapi3.NewContentWithSchema(openapi3.NewStringSchema(), []string{eventsType}))),
+				http.StatusBadRequest),
+		},
+		http.MethodPost + " /hmac": {
+			OperationID: "hmac",
+			Summary:     "Hex encoded HMAC-SHA512 of s with key",
+			RequestBody: jsonBody("HashMsgIn"),
+			Responses: withErrors(responses(http.StatusOK, textResponse("hmac")),
+				http.StatusBadRequest),
+		},
+		http.MethodPost + " /multiply": {
+			OperationID: "multiply",
+			Summary:     "Products of number pairs calculated by remote server",
+			RequestBody: jsonBody("Pairs"),
+			Responses: withErrors(responses(http.StatusOK, jsonResponse("products by pair key", "CounterValues")),
+				http.StatusBadRequest, http.StatusBadGateway),
+		},
+		http.MethodGet + " /ws": {
+			OperationID: "websocket",
+			Summary:     "Websocket of WSRequest commands and WSResponse replies and events",
+			Description: "Browsers which cant set " + APIKeyHeader + " offer subprotocols " + WSProtocol +
+				" and " + WSKeyProtocolPrefix + "<api key> instead.",
+			Responses: responses(
+				http.StatusSwitchingProtocols, openapi3.NewResponse().WithDescription("connection upgraded"),
+				http.StatusBadRequest, openapi3.NewResponse().WithDescription("not a websocket handshake"),
+			),
+		},
+	}
+}
+
+// notZero describes required numbers, validation rejects zero as blank.
+const notZero = "must not be zero"
+
+// modelSchemas generates component schemas from models and applies limits
+// checked by their Validate.
+func modelSchemas() (openapi3.Schemas, error) {
+	schemas := openapi3.Schemas{}
+	for name, v := range map[string]interface{}{
+		"IncrMsgIn":     models.IncrMsgIn{},
+		"IncrMsgOut":    models.IncrMsgOut{},
+		"IncrBatchIn":   models.IncrBatchIn{},
+		"SetMsgIn":      models.SetMsgIn{},
+		"HistoryMsgOut": models.HistoryMsgOut{},
+		"HashMsgIn":     models.HashMsgIn{},
+		"Pairs":         []*models.Pair{},
+		"HealthMsgOut":  HealthMsgOut{},
+		"Problem":       apierr.Problem{},
+		"WSRequest":     WSRequest{},
+		"WSResponse":    WSResponse{},
+	} {
+		s, err := openapi3gen.NewSchemaRefForValue(v, nil, openapi3gen.UseAllExportedFields())
+		if err != nil {
+			return nil, err
+		}
+		schemas[name] = s
+	}
+
+	key := func(s *openapi3.Schema) {
+		constrain(s, "key", func(p *openapi3.Schema) {
+			p.WithMinLength(models.MinKeyLen).WithMaxLength(models.MaxKeyLen)
+		})
+	}
+
+	incr := schemas["IncrMsgIn"].Value
+	key(incr)
+	incr.Required = []string{"key", "val"}
+	constrain(incr, "val", func(p *openapi3.Schema) {
+		p.Description = notZero
+	})
+	constrain(incr, "ttl", func(p *openapi3.Schema) {
+		p.WithMin(0).Description = "time to live in milliseconds"
+	})
+	constrain(incr, "ttl_on_create", func(p *openapi3.Schema) {
+		p.Description = models.ErrTTLOnCreate.Error()
+	})
+	constrain(incr, "max", func(p *openapi3.Schema) {
+		p.Description = models.ErrMaxLessMin.Error()
+	})
+
+	// v1 takes key from path, body may only repeat it
+	in := *incr
+	in.Properties = make(openapi3.Schemas, len(incr.Properties))
+	for k, v := range incr.Properties {
+		in.Properties[k] = v
+	}
+	constrain(&in, "key", func(p *openapi3.Schema) {
+		p.Description = ErrKeyMismatch.Error()
+	})
+	in.Required = []string{"val"}
+	schemas["IncrementIn"] = openapi3.NewSchemaRef("", &in)
+
+	schemas["IncrMsgOut"].Value.Required = []string{"key", "res", "ttl"}
+	constrain(schemas["IncrMsgOut"].Value, "ttl", func(p *openapi3.Schema) {
+		p.Description = "remaining time to live in milliseconds, -1 if counter never expires"
+	})
+
+	batch := schemas["IncrBatchIn"].Value
+	batch.WithMinItems(1).WithMaxItems(models.MaxBatchSize)
+	batch.Description = "keys must not repeat"
+	key(batch.Items.Value)
+	constrain(batch.Items.Value, "val", func(p *openapi3.Schema) {
+		p.Description = notZero
+	})
+	batch.Items.Value.Required = []string{"key", "val"}
+	batch.Items.Value.Nullable = false
+
+	schemas["SetMsgIn"].Value.Required = []string{"val"}
+
+	hash := schemas["HashMsgIn"].Value
+	key(hash)
+	constrain(hash, "s", func(p *openapi3.Schema) {
+		p.WithMinLength(models.MinStringLen).WithMaxLength(models.MaxStringLen)
+	})
+	hash.Required = []string{"s", "key"}
+
+	pairs := schemas["Pairs"].Value
+	key(pairs.Items.Value)
+	pairs.Items.Value.Required = []string{"a", "b", "key"}
+
+	problem := schemas["Problem"].Value
+	problem.Required = []string{"type", "title", "status", "code"}
+	constrain(problem, "errors", func(p *openapi3.Schema) {
+		p.Description = "messages by field name or array index, nested like the request"
+	})
+	constrain(problem, "code", func(p *openapi3.Schema) {
+		p.Enum = []interface{}{
+			apierr.CodeMalformed, apierr.CodeValidation, apierr.CodeBadArgument, apierr.CodeUnauthorized,
+			apierr.CodeQuotaExceeded, apierr.CodeNotFound, apierr.CodeNotInteger, apierr.CodeOutOfBounds,
+			apierr.CodeConflict, apierr.CodeRemote, apierr.CodeUnavailable, apierr.CodeInternal,
+		}
+	})
+	// extension members like current of out_of_bounds
+	problem.AdditionalProperties = openapi3.AdditionalProperties{Has: openapi3.BoolPtr(true)}
+
+	schemas["CounterValues"] = openapi3.NewSchemaRef("", openapi3.NewObjectSchema().
+		WithAdditionalProperties(openapi3.NewInt64Schema()))
+
+	return schemas, nil
+}
+
+// constrain replaces property of s with its copy changed by f, generator
+// shares one schema between properties of the same go type.
+func constrain(s *openapi3.Schema, name string, f func(*openapi3.Schema)) {
+	p := *s.Properties[name].Value
+	f(&p)
+	s.Properties[name] = openapi3.NewSchemaRef("", &p)
+}
+
+func ref(name string) *openapi3.SchemaRef {
+	return openapi3.NewSchemaRef("#/components/schemas/"+name, nil)
+}
+
+func jsonBody(schema string) *openapi3.RequestBodyRef {
+	return &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithRequired(true).
+		WithContent(openapi3.NewContentWithSchemaRef(ref(schema), []string{jsonType}))}
+}
+
+func jsonResponse(desc, schema string) *openapi3.Response {
+	return openapi3.NewResponse().WithDescription(desc).
+		WithContent(openapi3.NewContentWithSchemaRef(ref(schema), []string{jsonType}))
+}
+
+func textResponse(desc string) *openapi3.Response {
+	return openapi3.NewResponse().WithDescription(desc).
+		WithContent(openapi3.NewContentWithSchema(openapi3.NewStringSchema(), []string{textType}))
+}
+
+// responses builds responses of status and response pairs.
+func responses(pairs ...interface{}) *openapi3.Responses {
+	rs := openapi3.NewResponses()
+	rs.Delete("default")
+
+	for i := 0; i < len(pairs); i += 2 {
+		rs.Set(strconv.Itoa(pairs[i].(int)), &openapi3.ResponseRef{Value: pairs[i+1].(*openapi3.Response)})
+	}
+
+	return rs
+}
+
+// withErrors adds problem responses of codes and ones any api route may
+// return.
+func withErrors(rs *openapi3.Responses, codes ...int) *openapi3.Responses {
+	codes = append(codes, http.StatusUnauthorized, http.StatusForbidden,
+		http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	for _, code := range codes {
+		rs.Set(strconv.Itoa(code), &openapi3.ResponseRef{Value: openapi3.NewResponse().
+			WithDescription(http.StatusText(code)).
+			WithContent(openapi3.NewContentWithSchemaRef(ref("Problem"), []string{apierr.ContentType}))})
+	}
+
+	return rs
+}
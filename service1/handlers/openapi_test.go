@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"service1/database"
+	"service1/models"
+	"service1/services"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPI_CoversRouter(t *testing.T) {
+	doc, err := OpenAPI()
+	assert.NoError(t, err)
+
+	r := NewRouter(NewHandler(services.NewTService(nil, nil)), RouterConfig{Health: NewHealth()})
+
+	err = r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		item := doc.Paths.Value(path)
+		if !assert.NotNil(t, item, path) {
+			return nil
+		}
+
+		for _, m := range methods {
+			assert.NotNil(t, item.GetOperation(m), m+" "+path)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	r := NewRouter(NewHandler(services.NewTService(nil, nil)), RouterConfig{})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, OpenAPIPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	doc, err := openapi3.NewLoader().LoadFromData(rec.Body.Bytes())
+	assert.NoError(t, err)
+	assert.NoError(t, doc.Validate(context.Background()))
+}
+
+// TestOpenAPI_Responses checks real responses of handlers against document.
+func TestOpenAPI_Responses(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	assert.NoError(t, redisServer.Set("text", "oops"))
+
+	db := database.NewDB(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	defer db.Stop()
+
+	r := NewRouter(NewHandler(services.NewTService(db, nil)), RouterConfig{Health: NewHealth()})
+
+	doc, err := OpenAPI()
+	assert.NoError(t, err)
+
+	docRouter, err := gorillamux.NewRouter(doc)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		method       string
+		path         string
+		req          string
+		expectedCode int
+	}{
+		{http.MethodPost, "/v1/counters/x:increment", `{"val": 2}`, http.StatusOK},
+		{http.MethodPost, "/v1/counters/x:increment", `{"val": 2,"ttl": 1000}`, http.StatusOK},
+		{http.MethodPost, "/v1/counters/x:increment", `{"val": -9,"min": 0}`, http.StatusConflict},
+		{http.MethodPost, "/v1/counters/x:increment", `{"val": 0}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/counters/x:increment", `{"val": "1"}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/counters:batchIncrement", `[{"key": "x","val": 1},{"key": "y","val": 1}]`, http.StatusOK},
+		{http.MethodPost, "/v1/counters:batchIncrement", `[{"key": "x","val": 1},{"key": "x","val": 1}]`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/counters:batchIncrement", `[{"key": "text","val": 1}]`, http.StatusConflict},
+		{http.MethodGet, "/v1/counters/x", "", http.StatusOK},
+		{http.MethodGet, "/v1/counters/missing", "", http.StatusNotFound},
+		{http.MethodPut, "/v1/counters/x", `{"val": 7}`, http.StatusOK},
+		{http.MethodPut, "/v1/counters/x", `{}`, http.StatusBadRequest},
+		{http.MethodGet, "/v1/counters/x/history", "", http.StatusOK},
+		{http.MethodGet, "/v1/counters/x/history?step=1s", "", http.StatusBadRequest},
+		{http.MethodDelete, "/v1/counters/x", "", http.StatusNoContent},
+		{http.MethodDelete, "/v1/counters/x", "", http.StatusNotFound},
+		{http.MethodPost, "/v1/hmac", `{"s": "test","key": "test123"}`, http.StatusOK},
+		{http.MethodPost, "/v1/hmac", `{"s": "test"}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/multiply", `[{"a": "1","b": "2"}]`, http.StatusBadRequest},
+		{http.MethodPost, "/test1", `{"key": "x","val": 1}`, http.StatusOK},
+		{http.MethodPost, "/test1", `{"key": "x","val": 1,"ttl": 1000}`, http.StatusOK},
+		{http.MethodGet, "/counters/x", "", http.StatusOK},
+		{http.MethodGet, "/healthz", "", http.StatusOK},
+		{http.MethodGet, "/readyz", "", http.StatusOK},
+		{http.MethodGet, "/metrics", "", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.req))
+			req.Header.Set("Content-Type", "application/json")
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code, rec.Body.String())
+
+			route, params, err := docRouter.FindRoute(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: &openapi3filter.RequestValidationInput{
+					Request:    req,
+					PathParams: params,
+					Route:      route,
+				},
+				Status: rec.Code,
+				Header: rec.Header(),
+				Body:   io.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+			})
+			assert.NoError(t, err, rec.Body.String())
+		})
+	}
+}
+
+func TestOpenAPI_Constraints(t *testing.T) {
+	doc, err := OpenAPI()
+	assert.NoError(t, err)
+
+	bs, err := json.Marshal(doc.Components.Schemas["IncrBatchIn"])
+	assert.NoError(t, err)
+	assert.Contains(t, string(bs), `"maxItems":100`)
+	assert.Contains(t, string(bs), `"maxLength":20`)
+}
+
+// TestOpenAPI_MatchesValidation checks that documented schemas require what
+// Validate of models requires and document rules they check.
+func TestOpenAPI_MatchesValidation(t *testing.T) {
+	doc, err := OpenAPI()
+	assert.NoError(t, err)
+
+	object := func(name string) *openapi3.Schema {
+		s := doc.Components.Schemas[name].Value
+		if s.Items != nil {
+			return s.Items.Value
+		}
+		return s
+	}
+
+	testCases := []struct {
+		schema string
+		model  func() validation.Validatable
+		valid  string
+		// invalid bodies break rules of fields
+		invalid map[string]string
+	}{
+		{
+			schema: "IncrMsgIn",
+			model:  func() validation.Validatable { return &models.IncrMsgIn{} },
+			valid:  `{"key":"x","val":1,"ttl":5,"ttl_on_create":true,"min":0,"max":10}`,
+			invalid: map[string]string{
+				"key":           `{"key":"123456789012345678901","val":1}`,
+				"val":           `{"key":"x","val":0}`,
+				"ttl":           `{"key":"x","val":1,"ttl":-1}`,
+				"ttl_on_create": `{"key":"x","val":1,"ttl_on_create":true}`,
+				"max":           `{"key":"x","val":1,"min":5,"max":4}`,
+			},
+		},
+		{
+			schema: "IncrementIn",
+			model:  func() validation.Validatable { return &models.IncrMsgIn{Key: "x"} },
+			valid:  `{"key":"x","val":1,"ttl":5,"ttl_on_create":true,"min":0,"max":10}`,
+		},
+		{
+			schema: "IncrBatchIn",
+			model:  func() validation.Validatable { return &models.IncrBatchItem{} },
+			valid:  `{"key":"x","val":1}`,
+			invalid: map[string]string{
+				"key": `{"key":"123456789012345678901","val":1}`,
+				"val": `{"key":"x","val":0}`,
+			},
+		},
+		{
+			schema: "SetMsgIn",
+			model:  func() validation.Validatable { return &models.SetMsgIn{} },
+			valid:  `{"val":0}`,
+		},
+		{
+			schema: "HashMsgIn",
+			model:  func() validation.Validatable { return &models.HashMsgIn{} },
+			valid:  `{"s":"abc","key":"x"}`,
+			invalid: map[string]string{
+				"s":   `{"s":"123456789012345678901","key":"x"}`,
+				"key": `{"s":"abc","key":"123456789012345678901"}`,
+			},
+		},
+		{
+			schema: "Pairs",
+			model:  func() validation.Validatable { return &models.Pair{} },
+			valid:  `{"a":"2","b":"3","key":"x"}`,
+			invalid: map[string]string{
+				"key": `{"a":"2","b":"3","key":"123456789012345678901"}`,
+			},
+		},
+	}
+
+	// validate returns error of field, body is checked against schema too
+	validate := func(t *testing.T, s *openapi3.Schema, model func() validation.Validatable, body string) (error, error) {
+		var v interface{}
+		assert.NoError(t, json.Unmarshal([]byte(body), &v))
+
+		m := model()
+		assert.NoError(t, json.Unmarshal([]byte(body), m))
+
+		return m.Validate(), s.VisitJSON(v)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.schema, func(t *testing.T) {
+			s := object(tc.schema)
+
+			err, serr := validate(t, s, tc.model, tc.valid)
+			assert.NoError(t, err)
+			assert.NoError(t, serr)
+
+			for name := range s.Properties {
+				var body map[string]interface{}
+				assert.NoError(t, json.Unmarshal([]byte(tc.valid), &body))
+				delete(body, name)
+
+				bs, err := json.Marshal(body)
+				assert.NoError(t, err)
+
+				err, _ = validate(t, s, tc.model, string(bs))
+				errs, _ := err.(validation.Errors)
+				assert.Equal(t, contains(s.Required, name), errs[name] != nil, "required "+name)
+			}
+
+			for name, body := range tc.invalid {
+				err, serr := validate(t, s, tc.model, body)
+				errs, _ := err.(validation.Errors)
+				if !assert.Error(t, errs[name], name) {
+					continue
+				}
+
+				// rule is either checked by schema or described
+				if serr == nil {
+					desc := s.Properties[name].Value.Description
+					assert.NotEmpty(t, desc, name)
+					// zero numbers are reported as blank
+					if desc != notZero {
+						assert.Equal(t, errs[name].Error(), desc, name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// APIPrefix of current api version.
+const APIPrefix = "/v1"
+
+// deprecatedSince is when unversioned routes got v1 successors, sent as
+// Deprecation header of RFC 9745.
+var deprecatedSince = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// RouterConfig .
+type RouterConfig struct {
+	// Middleware wraps every route, usually result of Chain.
+	Middleware []mux.MiddlewareFunc
+	// APIKeys maps api keys to tenants, empty disables auth.
+	APIKeys map[string]string
+	// Health serves /healthz and /readyz when set.
+	Health *Health
+	// CORS routes preflight requests of any path to middleware.
+	CORS bool
+}
+
+// route of v1 api. Legacy is unversioned path kept for old clients.
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+	legacy  string
+}
+
+func (h *Handler) routes() []route {
+	return []route{
+		{http.MethodPost, "/counters/{key}:increment", h.IncrementCounterHandler(), ""},
+		{http.MethodPost, "/counters:batchIncrement", h.IncrementManyHandler(), "/counters:batchIncrement"},
+		{http.MethodGet, "/counters/{key}", h.GetCounterHandler(), "/counters/{key}"},
+		{http.MethodPut, "/counters/{key}", h.SetCounterHandler(), "/counters/{key}"},
+		{http.MethodDelete, "/counters/{key}", h.DeleteCounterHandler(), "/counters/{key}"},
+		{http.MethodGet, "/counters/{key}/history", h.CounterHistoryHandler(), "/counters/{key}/history"},
+		{http.MethodGet, "/counters/{key}/events", h.CounterEventsHandler(), "/counters/{key}/events"},
+		{http.MethodPost, "/hmac", h.HashStringHandler(), "/test2"},
+		{http.MethodPost, "/multiply", h.MulStringValHandler(), "/test3"},
+		{http.MethodGet, "/ws", h.WebSocketHandler(), "/ws"},
+	}
+}
+
+// NewRouter .
+func NewRouter(h *Handler, cfg RouterConfig) *mux.Router {
+	r := mux.NewRouter()
+	r.Use(cfg.Middleware...)
+	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) })
+
+	if cfg.Health != nil {
+		r.HandleFunc("/healthz", cfg.Health.LivenessHandler()).Methods(http.MethodGet)
+		r.HandleFunc("/readyz", cfg.Health.ReadinessHandler()).Methods(http.MethodGet)
+	}
+	r.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+	r.HandleFunc(OpenAPIPath, OpenAPIHandler()).Methods(http.MethodGet)
+
+	api := r.NewRoute().Subrouter()
+	api.Use(TenantAuth(cfg.APIKeys))
+
+	v1 := api.PathPrefix(APIPrefix).Subrouter()
+	for _, rt := range h.routes() {
+		succ := v1.HandleFunc(rt.path, rt.handler).Methods(rt.method)
+		if rt.legacy != "" {
+			api.Handle(rt.legacy, deprecated(succ, rt.handler)).Methods(rt.method)
+		}
+	}
+
+	// increment took key from body before it moved to path
+	api.Handle("/test1", deprecated(nil, h.IncrementByHandler())).Methods(http.MethodPost)
+
+	// preflight requests must match a route to reach cors middleware
+	if cfg.CORS {
+		r.PathPrefix("/").Methods(http.MethodOptions).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+
+	return r
+}
+
+// deprecated marks responses of legacy route, Link points to successor route
+// built from the same path variables.
+func deprecated(succ *mux.Route, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(deprecatedSince.Unix(), 10))
+
+		if succ != nil {
+			vars := mux.Vars(r)
+			pairs := make([]string, 0, len(vars)*2)
+			for k, v := range vars {
+				pairs = append(pairs, k, v)
+			}
+
+			if u, err := succ.URL(pairs...); err == nil {
+				w.Header().Set("Link", "<"+u.String()+`>; rel="successor-version"`)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
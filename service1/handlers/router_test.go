@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"service1/database"
+	"service1/services"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRouter(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	db := database.NewDB(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	defer db.Stop()
+
+	r := NewRouter(NewHandler(services.NewTService(db, nil)), RouterConfig{Health: NewHealth()})
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		req          string
+		res          string
+		expectedCode int
+		deprecated   bool
+		link         string
+	}{
+		{
+			name:         "increment",
+			method:       http.MethodPost,
+			path:         "/v1/counters/x:increment",
+			req:          `{"val": 2}`,
+			res:          `{"key":"x","res":2,"ttl":-1}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "increment with body key of path",
+			method:       http.MethodPost,
+			path:         "/v1/counters/x:increment",
+			req:          `{"key": "x","val": 1}`,
+			res:          `{"key":"x","res":3,"ttl":-1}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "increment with other body key",
+			method:       http.MethodPost,
+			path:         "/v1/counters/x:increment",
+			req:          `{"key": "y","val": 1}`,
+			res:          `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request has invalid fields","instance":"/v1/counters/x:increment","code":"validation_failed","errors":{"key":"must be the same as key in path"}}` + "\n",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "get",
+			method:       http.MethodGet,
+			path:         "/v1/counters/x",
+			res:          `{"x":3}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "batch increment",
+			method:       http.MethodPost,
+			path:         "/v1/counters:batchIncrement",
+			req:          `[{"key": "x","val": 1}]`,
+			res:          `{"x":4}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "legacy increment",
+			method:       http.MethodPost,
+			path:         "/test1",
+			req:          `{"key": "x","val": 1}`,
+			res:          `{"key":"x","res":5,"ttl":-1}` + "\n",
+			expectedCode: http.StatusOK,
+			deprecated:   true,
+		},
+		{
+			name:         "legacy get",
+			method:       http.MethodGet,
+			path:         "/counters/x",
+			res:          `{"x":5}` + "\n",
+			expectedCode: http.StatusOK,
+			deprecated:   true,
+			link:         `</v1/counters/x>; rel="successor-version"`,
+		},
+		{
+			name:         "hmac",
+			method:       http.MethodPost,
+			path:         "/v1/hmac",
+			req:          `{"s": "test","key": "test123"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "legacy hmac",
+			method:       http.MethodPost,
+			path:         "/test2",
+			req:          `{"s": "test","key": "test123"}`,
+			expectedCode: http.StatusOK,
+			deprecated:   true,
+			link:         `</v1/hmac>; rel="successor-version"`,
+		},
+		{
+			name:         "multiply validates",
+			method:       http.MethodPost,
+			path:         "/v1/multiply",
+			req:          `[{"a": "1","b": "2"}]`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "healthz",
+			method:       http.MethodGet,
+			path:         "/healthz",
+			res:          `{"status":"ok"}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "no v1 alias for placeholder",
+			method:       http.MethodPost,
+			path:         "/v1/test1",
+			req:          `{"key": "x","val": 1}`,
+			res:          "404 page not found\n",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.req))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			if tc.res != "" {
+				assert.Equal(t, tc.res, rec.Body.String())
+			}
+
+			dep := rec.Header().Get("Deprecation")
+			assert.Equal(t, tc.deprecated, strings.HasPrefix(dep, "@"), dep)
+			assert.Equal(t, tc.link, rec.Header().Get("Link"))
+		})
+	}
+}
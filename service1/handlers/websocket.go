@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"service1/apierr"
+	"service1/database"
+	"service1/logger"
+	"service1/models"
+	"sync"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = wsPongWait * 9 / 10
+	wsMaxMessageSize = 64 * 1024
+	wsSendBuffer     = 64
+)
+
+// Websocket commands.
+const (
+	WSOpIncr        = "incr"
+	WSOpGet         = "get"
+	WSOpSubscribe   = "subscribe"
+	WSOpUnsubscribe = "unsubscribe"
+	WSOpMul         = "mul"
+	WSOpEvent       = "event"
+)
+
+// ErrUnknownOp .
+var ErrUnknownOp = errors.New("unknown op")
+
+// WSRequest is a command sent by websocket client. ID is echoed back in the
+// response so client can match them.
+type WSRequest struct {
+	ID    string            `json:"id"`
+	Op    string            `json:"op"`
+	Key   string            `json:"key,omitempty"`
+	Incr  *models.IncrMsgIn `json:"incr,omitempty"`
+	Pairs []*models.Pair    `json:"pairs,omitempty"`
+}
+
+// WSResponse is either a reply to request with the same ID or a counter event.
+type WSResponse struct {
+	ID     string          `json:"id,omitempty"`
+	Op     string          `json:"op"`
+	Result interface{}     `json:"result,omitempty"`
+	Event  *database.Event `json:"event,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Code   apierr.Code     `json:"code,omitempty"`
+	// Fields are validation errors, the same as errors of problem details.
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// browsers fail handshake unless one of offered subprotocols is selected
+	Subprotocols: []string{WSProtocol},
+}
+
+// wsConn serves one websocket connection. All writes go through send channel
+// as websocket allows only one concurrent writer.
+type wsConn struct {
+	h    *Handler
+	conn *websocket.Conn
+	send chan *WSResponse
+
+	mu   sync.Mutex
+	subs map[string]context.CancelFunc
+}
+
+// WebSocketHandler .
+func (h *Handler) WebSocketHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// upgrader already replied with error
+			logger.FromContext(r.Context()).Debug("cant upgrade connection", "err", err)
+			return
+		}
+
+		c := &wsConn{
+			h:    h,
+			conn: conn,
+			send: make(chan *WSResponse, wsSendBuffer),
+			subs: make(map[string]context.CancelFunc),
+		}
+
+		// on shutdown write loop sends close frame
+		ctx, cancel := h.streamContext(r)
+		defer cancel()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			c.writeLoop(ctx)
+		}()
+
+		c.readLoop(ctx)
+
+		cancel()
+		<-done
+	}
+}
+
+func (c *wsConn) readLoop(ctx context.Context) {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, bs, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logger.FromContext(ctx).Warn("websocket closed", "err", err)
+			}
+			return
+		}
+
+		var res *WSResponse
+		var req WSRequest
+		if err := json.Unmarshal(bs, &req); err != nil {
+			res = &WSResponse{Error: ErrNotCorrectMsg.Error(), Code: apierr.CodeMalformed}
+		} else {
+			res = c.handle(ctx, &req)
+			res.ID = req.ID
+		}
+
+		select {
+		case c.send <- res:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// writeLoop sends responses, events and pings until context is done, then
+// closes connection gracefully.
+func (c *wsConn) writeLoop(ctx context.Context) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+			return
+		case res := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteJSON(res); err != nil {
+				logger.FromContext(ctx).Warn("cant write to websocket", "err", err)
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *wsConn) handle(ctx context.Context, req *WSRequest) *WSResponse {
+	res := &WSResponse{Op: req.Op}
+
+	var err error
+	switch req.Op {
+	case WSOpIncr:
+		if req.Incr == nil {
+			err = apierr.Malformed(ErrNotCorrectMsg)
+			break
+		}
+		if err = req.Incr.Validate(); err != nil {
+			err = apierr.Invalid(err)
+			break
+		}
+		res.Result, err = c.h.service.IncrementBy(ctx, req.Incr)
+
+	case WSOpGet:
+		if err = models.ValidateKey(req.Key); err != nil {
+			err = apierr.Invalid(err)
+			break
+		}
+		res.Result, err = c.h.service.Get(ctx, req.Key)
+
+	case WSOpSubscribe:
+		if err = models.ValidateKey(req.Key); err != nil {
+			err = apierr.Invalid(err)
+			break
+		}
+		err = c.subscribe(ctx, req.Key)
+
+	case WSOpUnsubscribe:
+		c.unsubscribe(req.Key)
+
+	case WSOpMul:
+		// nil pairs are rejected before pairs validate themselves
+		if err = validation.Validate(req.Pairs, validation.Required, validation.Each(validation.NotNil)); err != nil {
+			err = apierr.Invalid(err)
+			break
+		}
+		if res.Result, err = c.h.service.MulStringVal(ctx, req.Pairs); err != nil {
+			err = apierr.Remote(err)
+		}
+
+	default:
+		err = apierr.New(apierr.CodeBadArgument, ErrUnknownOp)
+	}
+
+	// only what problem details show is sent, internal errors have no detail
+	if err != nil {
+		e := apierr.From(err)
+		res.Result = nil
+		res.Error = e.Detail
+		if res.Error == "" {
+			res.Error = http.StatusText(e.Status())
+		}
+		res.Code = e.Code
+		res.Fields = e.Fields
+	}
+
+	return res
+}
+
+// subscribe forwards events of key to the connection until unsubscribe or
+// connection close. Subscribing twice to the same key is a no-op.
+func (c *wsConn) subscribe(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.subs[key]; ok {
+		return nil
+	}
+
+	subctx, cancel := context.WithCancel(ctx)
+	evs, err := c.h.service.Subscribe(subctx, key, 0)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	c.subs[key] = cancel
+
+	go func() {
+		for ev := range evs {
+			select {
+			case c.send <- &WSResponse{Op: WSOpEvent, Event: ev}:
+			case <-subctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (c *wsConn) unsubscribe(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cancel, ok := c.subs[key]; ok {
+		cancel()
+		delete(c.subs, key)
+	}
+}
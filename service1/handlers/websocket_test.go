@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"service1/database"
+	"service1/services"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerWebSocketHandler(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisServer.Addr(),
+	})
+
+	db := database.NewDB(redisClient)
+	defer db.Stop()
+
+	handler := NewHandler(services.NewTService(db, nil))
+
+	srv := httptest.NewServer(handler.WebSocketHandler())
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	testCases := []struct {
+		name string
+		req  string
+		res  string
+	}{
+		{
+			name: "get missing",
+			req:  `{"id": "1", "op": "get", "key": "x"}`,
+			res:  `{"id":"1","op":"get","error":"counter not found","code":"not_found"}`,
+		},
+		{
+			name: "subscribe",
+			req:  `{"id": "2", "op": "subscribe", "key": "x"}`,
+			res:  `{"id":"2","op":"subscribe"}`,
+		},
+		{
+			name: "incr",
+			req:  `{"id": "3", "op": "incr", "incr": {"key": "x", "val": 4}}`,
+			res:  `{"id":"3","op":"incr","result":{"key":"x","res":4,"ttl":-1}}`,
+		},
+		{
+			name: "get",
+			req:  `{"id": "4", "op": "get", "key": "x"}`,
+			res:  `{"id":"4","op":"get","result":{"x":4}}`,
+		},
+		{
+			name: "invalid incr",
+			req:  `{"id": "5", "op": "incr", "incr": {"key": "x"}}`,
+			res:  `{"id":"5","op":"incr","error":"request has invalid fields","code":"validation_failed","fields":{"val":"cannot be blank"}}`,
+		},
+		{
+			name: "mul with null pair",
+			req:  `{"id": "7", "op": "mul", "pairs": [null]}`,
+			res:  `{"id":"7","op":"mul","error":"request has invalid fields","code":"validation_failed","fields":{"0":"is required"}}`,
+		},
+		{
+			name: "unknown op",
+			req:  `{"id": "6", "op": "oops"}`,
+			res:  `{"id":"6","op":"oops","error":"unknown op","code":"bad_argument"}`,
+		},
+		{
+			name: "not json",
+			req:  `oops`,
+			res:  `{"op":"","error":"not correct msg","code":"malformed_request"}`,
+		},
+	}
+
+	var events []string
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(tc.req)))
+
+			// events of subscription may come before the reply
+			for {
+				_, bs, err := conn.ReadMessage()
+				assert.NoError(t, err)
+
+				if strings.HasPrefix(string(bs), `{"op":"event"`) {
+					events = append(events, string(bs))
+					continue
+				}
+
+				assert.Equal(t, tc.res, strings.TrimSpace(string(bs)))
+				return
+			}
+		})
+	}
+
+	if len(events) == 0 {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, bs, err := conn.ReadMessage()
+		assert.NoError(t, err)
+		events = append(events, string(bs))
+	}
+
+	assert.Len(t, events, 1)
+	assert.Contains(t, events[0], `"op":"incr","key":"x","val":4,"delta":4`)
+}
+
+func TestHandlerWebSocketHandler_Shutdown(t *testing.T) {
+	handler := NewHandler(services.NewTService(nil, nil))
+
+	srv := httptest.NewServer(handler.WebSocketHandler())
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, handler.Shutdown(ctx))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err)
+}
+
+func TestHandlerWebSocketHandler_APIKey(t *testing.T) {
+	r := NewRouter(NewHandler(services.NewTService(nil, nil)), RouterConfig{APIKeys: map[string]string{"key-a": "a"}})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	testCases := []struct {
+		name         string
+		protocols    []string
+		header       http.Header
+		expectedCode int
+		protocol     string
+	}{
+		{
+			name:         "no key",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "key in header",
+			header:       http.Header{APIKeyHeader: {"key-a"}},
+			expectedCode: http.StatusSwitchingProtocols,
+		},
+		{
+			name:         "key as subprotocol",
+			protocols:    []string{WSProtocol, WSKeyProtocolPrefix + "key-a"},
+			expectedCode: http.StatusSwitchingProtocols,
+			protocol:     WSProtocol,
+		},
+		{
+			name:         "unknown key as subprotocol",
+			protocols:    []string{WSProtocol, WSKeyProtocolPrefix + "key-c"},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dialer := websocket.Dialer{Subprotocols: tc.protocols}
+
+			conn, res, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws", tc.header)
+			if assert.NotNil(t, res) {
+				assert.Equal(t, tc.expectedCode, res.StatusCode)
+			}
+			if tc.expectedCode != http.StatusSwitchingProtocols {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			defer conn.Close()
+			assert.Equal(t, tc.protocol, conn.Subprotocol())
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Package logger builds leveled structured logger of the service and carries
+// request scoped logger in context.
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces values of sensitive attributes.
+const Redacted = "[REDACTED]"
+
+// ErrUnknownFormat .
+var ErrUnknownFormat = errors.New("unknown log format")
+
+// sensitive are attribute keys whose values are never written.
+var sensitive = map[string]bool{
+	"hmac_key":      true,
+	"api_key":       true,
+	"x-api-key":     true,
+	"authorization": true,
+	"password":      true,
+	"secret":        true,
+}
+
+// Secret is a string which is always redacted in logs, whatever its key is.
+type Secret string
+
+// LogValue .
+func (Secret) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
+
+// Config .
+type Config struct {
+	// Level is debug, info, warn or error.
+	Level string
+	// Format is json or text.
+	Format string
+	// Output is stdout, stderr or path of a file to append to.
+	Output string
+}
+
+// New returns logger and closer of its sink.
+func New(cfg Config) (*slog.Logger, io.Closer, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, nil, fmt.Errorf("cant parse log level %w", err)
+	}
+
+	var w io.WriteCloser
+	switch cfg.Output {
+	case "", "stdout":
+		w = nopCloser{os.Stdout}
+	case "stderr":
+		w = nopCloser{os.Stderr}
+	default:
+		f, err := os.OpenFile(cfg.Output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cant open log file %w", err)
+		}
+		w = f
+	}
+
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+
+	var h slog.Handler
+	switch cfg.Format {
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		w.Close()
+		return nil, nil, fmt.Errorf("%w %q", ErrUnknownFormat, cfg.Format)
+	}
+
+	return slog.New(h), w, nil
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if sensitive[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+
+	return a
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+type ctxKey int
+
+const (
+	loggerKey ctxKey = iota
+	requestIDKey
+)
+
+// WithContext stores request scoped logger in context.
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns logger stored in context or default one.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+
+	return slog.Default()
+}
+
+// WithRequestID .
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns id of request context belongs to or empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// requestIDRe limits accepted request ids, they are sent to remote server
+// inside frame header, so line breaks must not pass.
+var requestIDRe = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// ValidRequestID reports whether id passed by client can be used as is.
+func ValidRequestID(id string) bool {
+	return requestIDRe.MatchString(id)
+}
+
+// NewRequestID returns random 16 bytes in hex.
+func NewRequestID() string {
+	bs := make([]byte, 16)
+	rand.Read(bs)
+	return hex.EncodeToString(bs)
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: redact}))
+
+	log.Info("hashing", "str", "test", "hmac_key", "test123", "other", Secret("test123"), "X-API-Key", "k1")
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, "test", m["str"])
+	assert.Equal(t, Redacted, m["hmac_key"])
+	assert.Equal(t, Redacted, m["other"])
+	assert.Equal(t, Redacted, m["X-API-Key"])
+	assert.NotContains(t, buf.String(), "test123")
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  Config
+		err  bool
+	}{
+		{name: "defaults", cfg: Config{Level: "info"}},
+		{name: "text to stderr", cfg: Config{Level: "debug", Format: "text", Output: "stderr"}},
+		{name: "file", cfg: Config{Level: "warn", Output: filepath.Join(t.TempDir(), "service1.log")}},
+		{name: "unknown level", cfg: Config{Level: "verbose"}, err: true},
+		{name: "unknown format", cfg: Config{Level: "info", Format: "xml"}, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			log, closer, err := New(tc.cfg)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotNil(t, log)
+			assert.NoError(t, closer.Close())
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, slog.Default(), FromContext(ctx))
+	assert.Empty(t, RequestID(ctx))
+
+	log := slog.Default().With("a", 1)
+	ctx = WithRequestID(WithContext(ctx, log), "id")
+
+	assert.Equal(t, log, FromContext(ctx))
+	assert.Equal(t, "id", RequestID(ctx))
+}
@@ -1,76 +1,349 @@
 package main
 
+//go:generate buf generate
+
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
 	"service1/database"
+	"service1/grpcserver"
 	"service1/handlers"
+	"service1/logger"
 	"service1/services"
+	"service1/tracing"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/gorilla/mux"
-)
-
-const (
-	redishost  = "localhost"
-	redisport  = "6379"
-	remotehost = "localhost"
-	remoteport = "9000"
+	"google.golang.org/grpc"
 )
 
 var (
-	serverhost string
-	serverport string
+	serverhost      string
+	serverport      string
+	grpcport        string
+	remoteaddr      string
+	remotetrans     string
+	tenantsfile     string
+	retention       = database.DefaultRetention
+	backend         string
+	snapshot        string
+	snapshotint     time.Duration
+	datadir         string
+	fsync           string
+	compactint      time.Duration
+	redismode       string
+	redisaddrs      string
+	redismaster     string
+	redispass       string
+	logcfg          logger.Config
+	tracecfg        tracing.Config
+	mwcfg           = handlers.MiddlewareConfig{AccessLogOutput: os.Stdout}
+	corsorigins     string
+	shutdowntimeout time.Duration
 )
 
+var tenantNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
 func init() {
 	flag.StringVar(&serverhost, "host", "localhost", "provide host")
 	flag.StringVar(&serverport, "port", "8080", "provide port")
+	flag.StringVar(&grpcport, "grpc-port", "", "provide grpc port, disabled when empty")
+	flag.StringVar(&remoteaddr, "remote-addr", "localhost:9000", "provide address of service2, its tcp or h2c port")
+	flag.StringVar(&remotetrans, "remote-transport", "tcp", "provide transport to service2, tcp or h2c")
+	flag.StringVar(&tenantsfile, "tenants", "", "provide json file with tenants, their api keys and key quotas")
+	flag.DurationVar(&retention.Minute, "history-minute-retention", retention.Minute, "provide retention of per minute history, 0 disables it")
+	flag.DurationVar(&retention.Hour, "history-hour-retention", retention.Hour, "provide retention of per hour history, 0 disables it")
+	flag.StringVar(&backend, "db", "redis", "provide counters storage, redis, memory or file")
+	flag.StringVar(&redismode, "redis-mode", "standalone", "provide redis mode, standalone, sentinel or cluster")
+	flag.StringVar(&redisaddrs, "redis-addrs", "localhost:6379", "provide comma separated redis addresses, sentinels in sentinel mode")
+	flag.StringVar(&redismaster, "redis-master", "mymaster", "provide master name in sentinel mode")
+	flag.StringVar(&redispass, "redis-password", "", "provide redis password")
+	flag.StringVar(&snapshot, "snapshot", "", "provide snapshot file of memory storage, empty disables snapshots")
+	flag.DurationVar(&snapshotint, "snapshot-interval", time.Minute, "provide interval of memory storage snapshots")
+	flag.StringVar(&datadir, "data-dir", "data", "provide directory of file storage")
+	flag.StringVar(&fsync, "fsync", database.FsyncAlways, "provide fsync policy of file storage, always, interval or never")
+	flag.DurationVar(&compactint, "compact-interval", 10*time.Minute, "provide log compaction interval of file storage")
+	flag.StringVar(&logcfg.Level, "log-level", "info", "provide log level, debug, info, warn or error")
+	flag.StringVar(&logcfg.Format, "log-format", "json", "provide log format, json or text")
+	flag.StringVar(&logcfg.Output, "log-output", "stdout", "provide log output, stdout, stderr or file path")
+	flag.StringVar(&tracecfg.Exporter, "trace-exporter", tracing.ExporterNone, "provide trace exporter, none, stdout or otlp, stdout one writes to stderr")
+	flag.StringVar(&tracecfg.Endpoint, "otlp-endpoint", "localhost:4318", "provide otlp/http collector address")
+	flag.BoolVar(&mwcfg.Recovery, "recovery", true, "provide whether to recover handler panics into 500")
+	flag.StringVar(&mwcfg.AccessLog, "access-log", handlers.AccessLogJSON, "provide access log format, none, json or apache")
+	flag.StringVar(&corsorigins, "cors-origins", "", "provide comma separated cors origins, * allows any, empty disables cors")
+	flag.DurationVar(&mwcfg.CORS.MaxAge, "cors-max-age", 10*time.Minute, "provide how long browsers cache cors preflight")
+	flag.BoolVar(&mwcfg.Gzip, "gzip", false, "provide whether to gzip responses")
+	flag.DurationVar(&shutdowntimeout, "shutdown-timeout", 10*time.Second, "provide how long requests in flight are waited for on shutdown")
+	flag.BoolVar(&mwcfg.SecurityHeaders, "security-headers", true, "provide whether to set security headers")
+}
+
+type tenantConfig struct {
+	Name    string   `json:"name"`
+	APIKeys []string `json:"api_keys"`
+	Quota   int64    `json:"quota"`
 }
 
 func main() {
 
 	flag.Parse()
 
-	opts := &redis.Options{
-		Addr:     net.JoinHostPort(redishost, redisport),
-		Password: "",
-		DB:       0,
+	log, closer, err := logger.New(logcfg)
+	if err != nil {
+		panic(err)
+	}
+	defer closer.Close()
+
+	slog.SetDefault(log)
+
+	shutdown, err := tracing.Init(context.Background(), tracecfg)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := shutdown(context.Background()); err != nil {
+			log.Error("cant flush spans", "err", err)
+		}
+	}()
+
+	keys, quotas, err := loadTenants(tenantsfile)
+	if err != nil {
+		panic(err)
 	}
 
-	client := initRedis(opts)
-	db := database.NewDB(client)
+	db, stop, err := initDB()
+	if err != nil {
+		panic(err)
+	}
 	defer func() {
-		if err := db.Stop(); err != nil {
-			fmt.Println(err)
+		if err := stop(); err != nil {
+			log.Error("cant stop db", "err", err)
 		}
 	}()
 
-	connector := services.NewTCPConnector(net.JoinHostPort(remotehost, remoteport))
+	remote, err := newConnector()
+	if err != nil {
+		panic(err)
+	}
+
+	connector := services.NewMetricsConnector(remote)
 
-	serv := services.NewTService(db, connector)
+	serv := services.NewInstrumentedService(services.NewTService(database.NewTenantDB(db, quotas), connector))
 	h := handlers.NewHandler(serv)
 
-	r := mux.NewRouter()
-	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) })
-	r.HandleFunc("/test1", h.IncrementByHandler()).Methods(http.MethodPost)
-	r.HandleFunc("/test2", h.HashStringHandler()).Methods(http.MethodPost)
-	r.HandleFunc("/test3", h.MulStringValHandler()).Methods(http.MethodPost)
+	if corsorigins != "" {
+		mwcfg.CORS.Origins = strings.Split(corsorigins, ",")
+		mwcfg.CORS.Methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+		mwcfg.CORS.Headers = []string{"Content-Type", handlers.APIKeyHeader, handlers.RequestIDHeader, "traceparent"}
+	}
+
+	chain, err := handlers.Chain(mwcfg, log)
+	if err != nil {
+		panic(err)
+	}
+
+	health := handlers.NewHealth()
+	health.Register("service2", services.Reachable(connector))
+	if p, ok := db.(interface{ Ping(context.Context) error }); ok {
+		health.Register("redis", p.Ping)
+	}
+
+	r := handlers.NewRouter(h, handlers.RouterConfig{
+		Middleware: chain,
+		APIKeys:    keys,
+		Health:     health,
+		CORS:       len(mwcfg.CORS.Origins) > 0,
+	})
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if grpcport != "" {
+		gs := grpcserver.New(serv, keys)
+		defer stopGRPC(gs, shutdowntimeout)
+
+		go func(addr string) {
+			lis, err := net.Listen("tcp", addr)
+			if err != nil {
+				log.Error("cant listen grpc", "addr", addr, "err", err)
+				return
+			}
+
+			log.Info("grpc server started", "addr", addr)
+			if err := gs.Serve(lis); err != nil {
+				log.Error("grpc server stopped", "err", err)
+			}
+		}(net.JoinHostPort(serverhost, grpcport))
+	}
+
+	srv := &http.Server{Addr: net.JoinHostPort(serverhost, serverport), Handler: r}
+
+	go func() {
+		log.Info("service started", "addr", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("server stopped", "err", err)
+			cancel()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("shutting down")
+
+	// deferred funcs stop grpc server, db with its final snapshot, flush spans
+	// and close log in this order after requests in flight are served
+	sctx, scancel := context.WithTimeout(context.Background(), shutdowntimeout)
+	defer scancel()
 
-	fmt.Println("service started")
-	http.ListenAndServe(net.JoinHostPort(serverhost, serverport), r)
+	// server does not end websockets and event streams, handler does
+	streams := make(chan error, 1)
+	srv.RegisterOnShutdown(func() { streams <- h.Shutdown(sctx) })
 
+	if err := srv.Shutdown(sctx); err != nil {
+		log.Error("cant shutdown server", "err", err)
+	}
+
+	if err := <-streams; err != nil {
+		log.Error("cant close streams", "err", err)
+	}
+}
+
+// stopGRPC waits for running rpcs up to timeout, streams left after it are
+// closed.
+func stopGRPC(gs *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		gs.Stop()
+	}
+}
+
+// newConnector creates connector to service2 over transport selected by flags.
+func newConnector() (services.RemoteConnector, error) {
+	switch remotetrans {
+	case "tcp":
+		return services.NewTCPConnector(remoteaddr), nil
+	case "h2c":
+		return services.NewH2CConnector(remoteaddr), nil
+	default:
+		return nil, fmt.Errorf("unknown remote transport %q", remotetrans)
+	}
+}
+
+// initDB creates storage selected by flags and returns its stop func.
+func initDB() (database.DB, func() error, error) {
+	switch backend {
+	case "redis":
+		client, err := initRedis()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		db := database.NewDB(client)
+		db.Retention = retention
+
+		// service starts without redis and serves what does not need it
+		if err := db.Connect(context.Background()); err != nil {
+			slog.Warn("cant connect to redis, starting degraded", "err", err)
+		}
+
+		return db, db.Stop, nil
+
+	case "memory":
+		db, err := database.NewMemoryDB(snapshot, snapshotint)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		db.Retention = retention
+		return db, db.Stop, nil
+
+	case "file":
+		db, err := database.NewFileDB(datadir, fsync, compactint, retention)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return db, db.Stop, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown db %q", backend)
+}
+
+func initRedis() (redis.UniversalClient, error) {
+	addrs := strings.Split(redisaddrs, ",")
+
+	var client redis.UniversalClient
+	switch redismode {
+	case "standalone":
+		client = redis.NewClient(&redis.Options{
+			Addr:     addrs[0],
+			Password: redispass,
+		})
+	case "sentinel":
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    redismaster,
+			SentinelAddrs: addrs,
+			Password:      redispass,
+		})
+	case "cluster":
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    addrs,
+			Password: redispass,
+		})
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", redismode)
+	}
+
+	return client, nil
 }
 
-func initRedis(opts *redis.Options) *redis.Client {
-	client := redis.NewClient(opts)
-	_, err := client.Ping(client.Context()).Result()
+// loadTenants returns api key to tenant mapping and tenant key quotas.
+func loadTenants(path string) (map[string]string, map[string]int64, error) {
+	keys, quotas := make(map[string]string), make(map[string]int64)
+	if path == "" {
+		return keys, quotas, nil
+	}
 
+	bs, err := os.ReadFile(path)
 	if err != nil {
-		panic(fmt.Errorf("cant connect to client, %w", err))
+		return nil, nil, fmt.Errorf("cant read tenants file %w", err)
+	}
+
+	var tenants []tenantConfig
+	if err := json.Unmarshal(bs, &tenants); err != nil {
+		return nil, nil, fmt.Errorf("cant parse tenants file %w", err)
+	}
+
+	for _, t := range tenants {
+		if !tenantNameRe.MatchString(t.Name) {
+			return nil, nil, fmt.Errorf("not correct tenant name %q", t.Name)
+		}
+
+		for _, k := range t.APIKeys {
+			if _, ok := keys[k]; ok || k == "" {
+				return nil, nil, fmt.Errorf("empty or duplicate api key for tenant %v", t.Name)
+			}
+			keys[k] = t.Name
+		}
+
+		quotas[t.Name] = t.Quota
 	}
 
-	return client
+	return keys, quotas, nil
 }
@@ -0,0 +1,73 @@
+// Package metrics declares prometheus collectors of service1. They are
+// registered in default registry, which is exposed by promhttp.Handler.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "service1"
+
+var (
+	// HTTPRequests .
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of http requests by route template, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	// HTTPDuration .
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of http requests by route template and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	// HTTPInFlight .
+	HTTPInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Number of http requests being served by route template.",
+	}, []string{"route"})
+
+	// ServiceDuration .
+	ServiceDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "service_method_duration_seconds",
+		Help:      "Latency of service methods by method and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "result"})
+
+	// RedisErrors .
+	RedisErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redis_errors_total",
+		Help:      "Number of failed redis commands by command name, missing keys are not errors.",
+	}, []string{"cmd"})
+
+	// RemoteDuration .
+	RemoteDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "remote_op_duration_seconds",
+		Help:      "Latency of remote server dial, read and write operations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"op", "result"})
+
+	// RemoteConns .
+	RemoteConns = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "remote_connections_open",
+		Help:      "Number of open connections to remote server.",
+	})
+)
+
+// Result is a label value of operation outcome.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "ok"
+}
@@ -1,26 +1,157 @@
 package models
 
 import (
+	"errors"
+	"strconv"
+	"time"
+
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
 
+// Limits checked by validation, api docs are built from them too.
+const (
+	// MaxBatchSize .
+	MaxBatchSize = 100
+	// MinKeyLen and MaxKeyLen bound counter and hmac keys.
+	MinKeyLen = 1
+	MaxKeyLen = 20
+	// MinStringLen and MaxStringLen bound hashed string.
+	MinStringLen = 1
+	MaxStringLen = 20
+	// MinHistoryStep .
+	MinHistoryStep = time.Minute
+)
+
+// Validation errors of rules api docs describe.
+var (
+	// ErrDuplicateKey .
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrTTLOnCreate .
+	ErrTTLOnCreate = errors.New("can be set only with ttl")
+	// ErrMaxLessMin .
+	ErrMaxLessMin = errors.New("must not be less than min")
+)
+
 // IncrMsgIn .
 type IncrMsgIn struct {
 	Key string `json:"key"`
 	Val int64  `json:"val"`
+	// TTL in milliseconds.
+	TTL         int64 `json:"ttl,omitempty"`
+	TTLOnCreate bool  `json:"ttl_on_create,omitempty"`
+	// Min and Max are optional bounds for the resulting value.
+	Min *int64 `json:"min,omitempty"`
+	Max *int64 `json:"max,omitempty"`
 }
 
 // Validate .
 func (msg IncrMsgIn) Validate() error {
 	return validation.ValidateStruct(&msg,
-		validation.Field(&msg.Key, validation.Required, validation.Length(1, 20)),
+		validation.Field(&msg.Key, validation.Required, validation.Length(MinKeyLen, MaxKeyLen)),
 		validation.Field(&msg.Val, validation.Required),
+		validation.Field(&msg.TTL, validation.Min(int64(0))),
+		validation.Field(&msg.TTLOnCreate,
+			validation.When(msg.TTL == 0, validation.Empty.Error(ErrTTLOnCreate.Error()))),
+		validation.Field(&msg.Max,
+			validation.When(msg.Min != nil && msg.Max != nil, validation.By(notLess(msg.Min)))),
+	)
+}
+
+// IncrBatchItem .
+type IncrBatchItem struct {
+	Key string `json:"key"`
+	Val int64  `json:"val"`
+}
+
+// Validate .
+func (item IncrBatchItem) Validate() error {
+	return validation.ValidateStruct(&item,
+		validation.Field(&item.Key, validation.Required, validation.Length(MinKeyLen, MaxKeyLen)),
+		validation.Field(&item.Val, validation.Required),
 	)
 }
 
+// IncrBatchIn .
+type IncrBatchIn []*IncrBatchItem
+
+// Validate checks every item and rejects repeated keys.
+func (batch IncrBatchIn) Validate() error {
+	if err := validation.Validate([]*IncrBatchItem(batch),
+		validation.Required, validation.Length(1, MaxBatchSize), validation.Each(validation.NotNil)); err != nil {
+		return err
+	}
+
+	errs := validation.Errors{}
+	seen := make(map[string]struct{}, len(batch))
+
+	for i, item := range batch {
+		if item == nil {
+			continue
+		}
+
+		if _, ok := seen[item.Key]; ok {
+			errs[strconv.Itoa(i)] = validation.Errors{"key": ErrDuplicateKey}
+		}
+		seen[item.Key] = struct{}{}
+	}
+
+	return errs.Filter()
+}
+
+// SetMsgIn .
+type SetMsgIn struct {
+	Val *int64 `json:"val"`
+}
+
+// Validate .
+func (msg SetMsgIn) Validate() error {
+	return validation.ValidateStruct(&msg,
+		validation.Field(&msg.Val, validation.NotNil),
+	)
+}
+
+// ValidateKey .
+func ValidateKey(key string) error {
+	return validation.Validate(key, validation.Required, validation.Length(MinKeyLen, MaxKeyLen))
+}
+
+// HistoryQuery .
+type HistoryQuery struct {
+	Key  string
+	From time.Time
+	To   time.Time
+	Step time.Duration
+}
+
+// Validate .
+func (q HistoryQuery) Validate() error {
+	return validation.ValidateStruct(&q,
+		validation.Field(&q.Key, validation.Required, validation.Length(MinKeyLen, MaxKeyLen)),
+		validation.Field(&q.From, validation.Required),
+		validation.Field(&q.To, validation.Required, validation.Min(q.From.Add(time.Nanosecond)).Error("must be after from")),
+		validation.Field(&q.Step, validation.Required, validation.Min(MinHistoryStep)),
+	)
+}
+
+// Point .
+type Point struct {
+	Time  time.Time `json:"t"`
+	Delta int64     `json:"delta"`
+}
+
+// HistoryMsgOut .
+type HistoryMsgOut struct {
+	Key    string   `json:"key"`
+	Step   string   `json:"step"`
+	Points []*Point `json:"points"`
+}
+
 // IncrMsgOut .
 type IncrMsgOut struct {
-	Res int64 `json:"res"`
+	Key string `json:"key"`
+	Res int64  `json:"res"`
+	// TTL is remaining time to live in milliseconds, -1 if counter never expires.
+	TTL int64 `json:"ttl"`
 }
 
 // HashMsgIn .
@@ -32,16 +163,16 @@ type HashMsgIn struct {
 // Validate .
 func (msg HashMsgIn) Validate() error {
 	return validation.ValidateStruct(&msg,
-		validation.Field(&msg.S, validation.Required, validation.Length(1, 20)),
-		validation.Field(&msg.Key, validation.Required, validation.Length(1, 20)),
+		validation.Field(&msg.S, validation.Required, validation.Length(MinStringLen, MaxStringLen)),
+		validation.Field(&msg.Key, validation.Required, validation.Length(MinKeyLen, MaxKeyLen)),
 	)
 }
 
 // Pair .
 type Pair struct {
-	A   string `josn:"a"`
+	A   string `json:"a"`
 	B   string `json:"b"`
-	Key string `josn:"key"`
+	Key string `json:"key"`
 }
 
 // Validate .
@@ -49,6 +180,15 @@ func (p Pair) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.A, validation.Required),
 		validation.Field(&p.B, validation.Required),
-		validation.Field(&p.Key, validation.Required, validation.Length(1, 20)),
+		validation.Field(&p.Key, validation.Required, validation.Length(MinKeyLen, MaxKeyLen)),
 	)
 }
+
+func notLess(min *int64) validation.RuleFunc {
+	return func(value interface{}) error {
+		if max, _ := value.(*int64); max != nil && *max < *min {
+			return ErrMaxLessMin
+		}
+		return nil
+	}
+}
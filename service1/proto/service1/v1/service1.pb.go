@@ -0,0 +1,652 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.32.0
+// 	protoc        (unknown)
+// source: service1/v1/service1.proto
+
+package service1v1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type IncrementByRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Val int64  `protobuf:"varint,2,opt,name=val,proto3" json:"val,omitempty"`
+	// ttl in milliseconds.
+	Ttl         int64  `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	TtlOnCreate bool   `protobuf:"varint,4,opt,name=ttl_on_create,json=ttlOnCreate,proto3" json:"ttl_on_create,omitempty"`
+	Min         *int64 `protobuf:"varint,5,opt,name=min,proto3,oneof" json:"min,omitempty"`
+	Max         *int64 `protobuf:"varint,6,opt,name=max,proto3,oneof" json:"max,omitempty"`
+}
+
+func (x *IncrementByRequest) Reset() {
+	*x = IncrementByRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service1_v1_service1_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IncrementByRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncrementByRequest) ProtoMessage() {}
+
+func (x *IncrementByRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service1_v1_service1_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncrementByRequest.ProtoReflect.Descriptor instead.
+func (*IncrementByRequest) Descriptor() ([]byte, []int) {
+	return file_service1_v1_service1_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *IncrementByRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *IncrementByRequest) GetVal() int64 {
+	if x != nil {
+		return x.Val
+	}
+	return 0
+}
+
+func (x *IncrementByRequest) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *IncrementByRequest) GetTtlOnCreate() bool {
+	if x != nil {
+		return x.TtlOnCreate
+	}
+	return false
+}
+
+func (x *IncrementByRequest) GetMin() int64 {
+	if x != nil && x.Min != nil {
+		return *x.Min
+	}
+	return 0
+}
+
+func (x *IncrementByRequest) GetMax() int64 {
+	if x != nil && x.Max != nil {
+		return *x.Max
+	}
+	return 0
+}
+
+type IncrementByResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Res int64  `protobuf:"varint,2,opt,name=res,proto3" json:"res,omitempty"`
+	// ttl is remaining time to live in milliseconds, -1 if counter never
+	// expires.
+	Ttl int64 `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+}
+
+func (x *IncrementByResponse) Reset() {
+	*x = IncrementByResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service1_v1_service1_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IncrementByResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncrementByResponse) ProtoMessage() {}
+
+func (x *IncrementByResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service1_v1_service1_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncrementByResponse.ProtoReflect.Descriptor instead.
+func (*IncrementByResponse) Descriptor() ([]byte, []int) {
+	return file_service1_v1_service1_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *IncrementByResponse) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *IncrementByResponse) GetRes() int64 {
+	if x != nil {
+		return x.Res
+	}
+	return 0
+}
+
+func (x *IncrementByResponse) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+type HashStringRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	S   string `protobuf:"bytes,1,opt,name=s,proto3" json:"s,omitempty"`
+	Key string `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (x *HashStringRequest) Reset() {
+	*x = HashStringRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service1_v1_service1_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HashStringRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HashStringRequest) ProtoMessage() {}
+
+func (x *HashStringRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service1_v1_service1_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HashStringRequest.ProtoReflect.Descriptor instead.
+func (*HashStringRequest) Descriptor() ([]byte, []int) {
+	return file_service1_v1_service1_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HashStringRequest) GetS() string {
+	if x != nil {
+		return x.S
+	}
+	return ""
+}
+
+func (x *HashStringRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type HashStringResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hmac string `protobuf:"bytes,1,opt,name=hmac,proto3" json:"hmac,omitempty"`
+}
+
+func (x *HashStringResponse) Reset() {
+	*x = HashStringResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service1_v1_service1_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HashStringResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HashStringResponse) ProtoMessage() {}
+
+func (x *HashStringResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service1_v1_service1_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HashStringResponse.ProtoReflect.Descriptor instead.
+func (*HashStringResponse) Descriptor() ([]byte, []int) {
+	return file_service1_v1_service1_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HashStringResponse) GetHmac() string {
+	if x != nil {
+		return x.Hmac
+	}
+	return ""
+}
+
+type Pair struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	A   string `protobuf:"bytes,1,opt,name=a,proto3" json:"a,omitempty"`
+	B   string `protobuf:"bytes,2,opt,name=b,proto3" json:"b,omitempty"`
+	Key string `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (x *Pair) Reset() {
+	*x = Pair{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service1_v1_service1_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Pair) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pair) ProtoMessage() {}
+
+func (x *Pair) ProtoReflect() protoreflect.Message {
+	mi := &file_service1_v1_service1_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pair.ProtoReflect.Descriptor instead.
+func (*Pair) Descriptor() ([]byte, []int) {
+	return file_service1_v1_service1_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Pair) GetA() string {
+	if x != nil {
+		return x.A
+	}
+	return ""
+}
+
+func (x *Pair) GetB() string {
+	if x != nil {
+		return x.B
+	}
+	return ""
+}
+
+func (x *Pair) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type MulStringValRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pairs []*Pair `protobuf:"bytes,1,rep,name=pairs,proto3" json:"pairs,omitempty"`
+}
+
+func (x *MulStringValRequest) Reset() {
+	*x = MulStringValRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service1_v1_service1_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MulStringValRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MulStringValRequest) ProtoMessage() {}
+
+func (x *MulStringValRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service1_v1_service1_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MulStringValRequest.ProtoReflect.Descriptor instead.
+func (*MulStringValRequest) Descriptor() ([]byte, []int) {
+	return file_service1_v1_service1_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *MulStringValRequest) GetPairs() []*Pair {
+	if x != nil {
+		return x.Pairs
+	}
+	return nil
+}
+
+type MulStringValResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// products by pair key.
+	Products map[string]int64 `protobuf:"bytes,1,rep,name=products,proto3" json:"products,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+}
+
+func (x *MulStringValResponse) Reset() {
+	*x = MulStringValResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_service1_v1_service1_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MulStringValResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MulStringValResponse) ProtoMessage() {}
+
+func (x *MulStringValResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service1_v1_service1_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MulStringValResponse.ProtoReflect.Descriptor instead.
+func (*MulStringValResponse) Descriptor() ([]byte, []int) {
+	return file_service1_v1_service1_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *MulStringValResponse) GetProducts() map[string]int64 {
+	if x != nil {
+		return x.Products
+	}
+	return nil
+}
+
+var File_service1_v1_service1_proto protoreflect.FileDescriptor
+
+var file_service1_v1_service1_proto_rawDesc = []byte{
+	0x0a, 0x1a, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2f, 0x76, 0x31, 0x2f, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0b, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2e, 0x76, 0x31, 0x22, 0xac, 0x01, 0x0a, 0x12, 0x49, 0x6e,
+	0x63, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x42, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x76, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x03, 0x76, 0x61, 0x6c, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x74, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x03, 0x74, 0x74, 0x6c, 0x12, 0x22, 0x0a, 0x0d, 0x74, 0x74, 0x6c, 0x5f, 0x6f, 0x6e,
+	0x5f, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x74,
+	0x74, 0x6c, 0x4f, 0x6e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x12, 0x15, 0x0a, 0x03, 0x6d, 0x69,
+	0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x03, 0x6d, 0x69, 0x6e, 0x88, 0x01,
+	0x01, 0x12, 0x15, 0x0a, 0x03, 0x6d, 0x61, 0x78, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x48, 0x01,
+	0x52, 0x03, 0x6d, 0x61, 0x78, 0x88, 0x01, 0x01, 0x42, 0x06, 0x0a, 0x04, 0x5f, 0x6d, 0x69, 0x6e,
+	0x42, 0x06, 0x0a, 0x04, 0x5f, 0x6d, 0x61, 0x78, 0x22, 0x4b, 0x0a, 0x13, 0x49, 0x6e, 0x63, 0x72,
+	0x65, 0x6d, 0x65, 0x6e, 0x74, 0x42, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x72, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03,
+	0x72, 0x65, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x74, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x03, 0x74, 0x74, 0x6c, 0x22, 0x33, 0x0a, 0x11, 0x48, 0x61, 0x73, 0x68, 0x53, 0x74, 0x72,
+	0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0c, 0x0a, 0x01, 0x73, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x01, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x22, 0x28, 0x0a, 0x12, 0x48, 0x61,
+	0x73, 0x68, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x12, 0x0a, 0x04, 0x68, 0x6d, 0x61, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x68, 0x6d, 0x61, 0x63, 0x22, 0x34, 0x0a, 0x04, 0x50, 0x61, 0x69, 0x72, 0x12, 0x0c, 0x0a, 0x01,
+	0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x01, 0x61, 0x12, 0x0c, 0x0a, 0x01, 0x62, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x01, 0x62, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x22, 0x3e, 0x0a, 0x13, 0x4d, 0x75,
+	0x6c, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x56, 0x61, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x27, 0x0a, 0x05, 0x70, 0x61, 0x69, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x11, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2e, 0x76, 0x31, 0x2e, 0x50,
+	0x61, 0x69, 0x72, 0x52, 0x05, 0x70, 0x61, 0x69, 0x72, 0x73, 0x22, 0xa0, 0x01, 0x0a, 0x14, 0x4d,
+	0x75, 0x6c, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x56, 0x61, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x4b, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2f, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x31,
+	0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x75, 0x6c, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x56, 0x61, 0x6c,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x50, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73,
+	0x1a, 0x3b, 0x0a, 0x0d, 0x50, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x32, 0x82, 0x02,
+	0x0a, 0x0a, 0x41, 0x50, 0x49, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x50, 0x0a, 0x0b,
+	0x49, 0x6e, 0x63, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x42, 0x79, 0x12, 0x1f, 0x2e, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x63, 0x72, 0x65, 0x6d,
+	0x65, 0x6e, 0x74, 0x42, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x63, 0x72, 0x65,
+	0x6d, 0x65, 0x6e, 0x74, 0x42, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4d,
+	0x0a, 0x0a, 0x48, 0x61, 0x73, 0x68, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x12, 0x1e, 0x2e, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x61, 0x73, 0x68, 0x53,
+	0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x61, 0x73, 0x68, 0x53,
+	0x74, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x53, 0x0a,
+	0x0c, 0x4d, 0x75, 0x6c, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x56, 0x61, 0x6c, 0x12, 0x20, 0x2e,
+	0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x75, 0x6c, 0x53,
+	0x74, 0x72, 0x69, 0x6e, 0x67, 0x56, 0x61, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x21, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x75,
+	0x6c, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x56, 0x61, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x42, 0x27, 0x5a, 0x25, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2f, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x2f, 0x76, 0x31,
+	0x3b, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x31, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
+}
+
+var (
+	file_service1_v1_service1_proto_rawDescOnce sync.Once
+	file_service1_v1_service1_proto_rawDescData = file_service1_v1_service1_proto_rawDesc
+)
+
+func file_service1_v1_service1_proto_rawDescGZIP() []byte {
+	file_service1_v1_service1_proto_rawDescOnce.Do(func() {
+		file_service1_v1_service1_proto_rawDescData = protoimpl.X.CompressGZIP(file_service1_v1_service1_proto_rawDescData)
+	})
+	return file_service1_v1_service1_proto_rawDescData
+}
+
+var file_service1_v1_service1_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_service1_v1_service1_proto_goTypes = []interface{}{
+	(*IncrementByRequest)(nil),   // 0: service1.v1.IncrementByRequest
+	(*IncrementByResponse)(nil),  // 1: service1.v1.IncrementByResponse
+	(*HashStringRequest)(nil),    // 2: service1.v1.HashStringRequest
+	(*HashStringResponse)(nil),   // 3: service1.v1.HashStringResponse
+	(*Pair)(nil),                 // 4: service1.v1.Pair
+	(*MulStringValRequest)(nil),  // 5: service1.v1.MulStringValRequest
+	(*MulStringValResponse)(nil), // 6: service1.v1.MulStringValResponse
+	nil,                          // 7: service1.v1.MulStringValResponse.ProductsEntry
+}
+var file_service1_v1_service1_proto_depIdxs = []int32{
+	4, // 0: service1.v1.MulStringValRequest.pairs:type_name -> service1.v1.Pair
+	7, // 1: service1.v1.MulStringValResponse.products:type_name -> service1.v1.MulStringValResponse.ProductsEntry
+	0, // 2: service1.v1.APIService.IncrementBy:input_type -> service1.v1.IncrementByRequest
+	2, // 3: service1.v1.APIService.HashString:input_type -> service1.v1.HashStringRequest
+	5, // 4: service1.v1.APIService.MulStringVal:input_type -> service1.v1.MulStringValRequest
+	1, // 5: service1.v1.APIService.IncrementBy:output_type -> service1.v1.IncrementByResponse
+	3, // 6: service1.v1.APIService.HashString:output_type -> service1.v1.HashStringResponse
+	6, // 7: service1.v1.APIService.MulStringVal:output_type -> service1.v1.MulStringValResponse
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_service1_v1_service1_proto_init() }
+func file_service1_v1_service1_proto_init() {
+	if File_service1_v1_service1_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_service1_v1_service1_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IncrementByRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service1_v1_service1_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IncrementByResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service1_v1_service1_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HashStringRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service1_v1_service1_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HashStringResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service1_v1_service1_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Pair); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service1_v1_service1_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MulStringValRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_service1_v1_service1_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MulStringValResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_service1_v1_service1_proto_msgTypes[0].OneofWrappers = []interface{}{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_service1_v1_service1_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_service1_v1_service1_proto_goTypes,
+		DependencyIndexes: file_service1_v1_service1_proto_depIdxs,
+		MessageInfos:      file_service1_v1_service1_proto_msgTypes,
+	}.Build()
+	File_service1_v1_service1_proto = out.File
+	file_service1_v1_service1_proto_rawDesc = nil
+	file_service1_v1_service1_proto_goTypes = nil
+	file_service1_v1_service1_proto_depIdxs = nil
+}
@@ -0,0 +1,191 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: service1/v1/service1.proto
+
+package service1v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	APIService_IncrementBy_FullMethodName  = "/service1.v1.APIService/IncrementBy"
+	APIService_HashString_FullMethodName   = "/service1.v1.APIService/HashString"
+	APIService_MulStringVal_FullMethodName = "/service1.v1.APIService/MulStringVal"
+)
+
+// APIServiceClient is the client API for APIService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type APIServiceClient interface {
+	// IncrementBy increments counter by val, optionally within bounds and
+	// with ttl.
+	IncrementBy(ctx context.Context, in *IncrementByRequest, opts ...grpc.CallOption) (*IncrementByResponse, error)
+	// HashString returns hex encoded HMAC-SHA512 of s with key.
+	HashString(ctx context.Context, in *HashStringRequest, opts ...grpc.CallOption) (*HashStringResponse, error)
+	// MulStringVal returns products of pairs calculated by remote server.
+	MulStringVal(ctx context.Context, in *MulStringValRequest, opts ...grpc.CallOption) (*MulStringValResponse, error)
+}
+
+type aPIServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAPIServiceClient(cc grpc.ClientConnInterface) APIServiceClient {
+	return &aPIServiceClient{cc}
+}
+
+func (c *aPIServiceClient) IncrementBy(ctx context.Context, in *IncrementByRequest, opts ...grpc.CallOption) (*IncrementByResponse, error) {
+	out := new(IncrementByResponse)
+	err := c.cc.Invoke(ctx, APIService_IncrementBy_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIServiceClient) HashString(ctx context.Context, in *HashStringRequest, opts ...grpc.CallOption) (*HashStringResponse, error) {
+	out := new(HashStringResponse)
+	err := c.cc.Invoke(ctx, APIService_HashString_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIServiceClient) MulStringVal(ctx context.Context, in *MulStringValRequest, opts ...grpc.CallOption) (*MulStringValResponse, error) {
+	out := new(MulStringValResponse)
+	err := c.cc.Invoke(ctx, APIService_MulStringVal_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// APIServiceServer is the server API for APIService service.
+// All implementations must embed UnimplementedAPIServiceServer
+// for forward compatibility
+type APIServiceServer interface {
+	// IncrementBy increments counter by val, optionally within bounds and
+	// with ttl.
+	IncrementBy(context.Context, *IncrementByRequest) (*IncrementByResponse, error)
+	// HashString returns hex encoded HMAC-SHA512 of s with key.
+	HashString(context.Context, *HashStringRequest) (*HashStringResponse, error)
+	// MulStringVal returns products of pairs calculated by remote server.
+	MulStringVal(context.Context, *MulStringValRequest) (*MulStringValResponse, error)
+	mustEmbedUnimplementedAPIServiceServer()
+}
+
+// UnimplementedAPIServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAPIServiceServer struct {
+}
+
+func (UnimplementedAPIServiceServer) IncrementBy(context.Context, *IncrementByRequest) (*IncrementByResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IncrementBy not implemented")
+}
+func (UnimplementedAPIServiceServer) HashString(context.Context, *HashStringRequest) (*HashStringResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HashString not implemented")
+}
+func (UnimplementedAPIServiceServer) MulStringVal(context.Context, *MulStringValRequest) (*MulStringValResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MulStringVal not implemented")
+}
+func (UnimplementedAPIServiceServer) mustEmbedUnimplementedAPIServiceServer() {}
+
+// UnsafeAPIServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to APIServiceServer will
+// result in compilation errors.
+type UnsafeAPIServiceServer interface {
+	mustEmbedUnimplementedAPIServiceServer()
+}
+
+func RegisterAPIServiceServer(s grpc.ServiceRegistrar, srv APIServiceServer) {
+	s.RegisterService(&APIService_ServiceDesc, srv)
+}
+
+func _APIService_IncrementBy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrementByRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServiceServer).IncrementBy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: APIService_IncrementBy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServiceServer).IncrementBy(ctx, req.(*IncrementByRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _APIService_HashString_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HashStringRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServiceServer).HashString(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: APIService_HashString_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServiceServer).HashString(ctx, req.(*HashStringRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _APIService_MulStringVal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MulStringValRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServiceServer).MulStringVal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: APIService_MulStringVal_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServiceServer).MulStringVal(ctx, req.(*MulStringValRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// APIService_ServiceDesc is the grpc.ServiceDesc for APIService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var APIService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "service1.v1.APIService",
+	HandlerType: (*APIServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "IncrementBy",
+			Handler:    _APIService_IncrementBy_Handler,
+		},
+		{
+			MethodName: "HashString",
+			Handler:    _APIService_HashString_Handler,
+		},
+		{
+			MethodName: "MulStringVal",
+			Handler:    _APIService_MulStringVal_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "service1/v1/service1.proto",
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// H2CPath is path remote server serves frames on over http/2.
+const H2CPath = "/multiply"
+
+// maxErrBody limits how much of error answer is put into error.
+const maxErrBody = 512
+
+// H2CTimeout bounds requests to remote server whose context has no earlier
+// deadline, so stalled server does not hang them forever.
+const H2CTimeout = 30 * time.Second
+
+// connection is pinged when nothing is read for h2cIdleTimeout and closed
+// when ping is not answered in h2cPingTimeout.
+const (
+	h2cIdleTimeout = 15 * time.Second
+	h2cPingTimeout = 5 * time.Second
+)
+
+// ErrFrameSent .
+var ErrFrameSent = errors.New("frame is already sent")
+
+// H2CConnector sends frames to remote server as bodies of http/2 requests
+// without tls, answers are read from response bodies. Unlike TCPConnector it
+// keeps one connection and multiplexes requests over it.
+type H2CConnector struct {
+	url    string
+	client *http.Client
+}
+
+// NewH2CConnector .
+func NewH2CConnector(addr string) *H2CConnector {
+	return &H2CConnector{
+		url: "http://" + addr + H2CPath,
+		client: &http.Client{
+			Timeout: H2CTimeout,
+			Transport: &http2.Transport{
+				AllowHTTP:       true,
+				ReadIdleTimeout: h2cIdleTimeout,
+				PingTimeout:     h2cPingTimeout,
+				DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, network, addr)
+				},
+			},
+		},
+	}
+}
+
+// Connect returns conn which buffers written frame and sends it on first
+// read, so callers use it like a tcp one.
+func (c *H2CConnector) Connect(ctx context.Context) (io.ReadWriteCloser, error) {
+	return &h2cConn{parent: ctx, ctx: ctx, c: c}, nil
+}
+
+// Ping .
+func (c *H2CConnector) Ping(ctx context.Context) (*RemoteHealth, error) {
+	return ping(ctx, c)
+}
+
+type h2cConn struct {
+	// parent is context of Connect, deadlines are set on its children
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	c      *H2CConnector
+	buf    bytes.Buffer
+	body   io.ReadCloser
+}
+
+func (c *h2cConn) Write(p []byte) (int, error) {
+	if c.body != nil {
+		return 0, ErrFrameSent
+	}
+
+	return c.buf.Write(p)
+}
+
+func (c *h2cConn) Read(p []byte) (int, error) {
+	if c.body == nil {
+		if err := c.send(); err != nil {
+			return 0, err
+		}
+	}
+
+	return c.body.Read(p)
+}
+
+func (c *h2cConn) send() error {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.c.url, &c.buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	res, err := c.c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cant connect to remote server %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+
+		bs, _ := io.ReadAll(io.LimitReader(res.Body, maxErrBody))
+		return fmt.Errorf("remote server answered %s: %s", res.Status, bytes.TrimSpace(bs))
+	}
+
+	c.body = res.Body
+	return nil
+}
+
+// SetDeadline bounds request, it has to be set before first read. Deadline
+// set before is replaced.
+func (c *h2cConn) SetDeadline(d time.Time) error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	c.ctx, c.cancel = context.WithDeadline(c.parent, d)
+	return nil
+}
+
+func (c *h2cConn) Close() error {
+	if c.cancel != nil {
+		defer c.cancel()
+	}
+
+	if c.body != nil {
+		return c.body.Close()
+	}
+
+	return nil
+}
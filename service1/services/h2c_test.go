@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"service1/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// newH2CServer serves frames over http/2 without tls, answers are looked up
+// by frame, unknown frames hang until request is canceled.
+func newH2CServer(t *testing.T, answers map[string]string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(H2CPath, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 2, r.ProtoMajor)
+		assert.Equal(t, http.MethodPost, r.Method)
+
+		bs, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		res, ok := answers[string(bs)]
+		if !ok {
+			<-r.Context().Done()
+			return
+		}
+
+		if strings.HasPrefix(res, "400 ") {
+			http.Error(w, strings.TrimPrefix(res, "400 "), http.StatusBadRequest)
+			return
+		}
+
+		io.WriteString(w, res)
+	})
+
+	srv := httptest.NewServer(h2c.NewHandler(mux, &http2.Server{}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestH2CConnector_MulStringVal(t *testing.T) {
+	srv := newH2CServer(t, map[string]string{
+		"12,43\r\n11,3\r\n\r\n ": "516\r\n33\r\n\r\n ",
+		"oops,3\r\n\r\n ":        "400 not correct format",
+	})
+
+	testCases := []struct {
+		name        string
+		pairs       []*models.Pair
+		timeout     time.Duration
+		expected    map[string]int
+		expectedErr string
+	}{
+		{
+			name:     "ok",
+			pairs:    []*models.Pair{{A: "12", B: "43", Key: "x"}, {A: "11", B: "3", Key: "y"}},
+			expected: map[string]int{"x": 516, "y": 33},
+		},
+		{
+			name:        "rejected",
+			pairs:       []*models.Pair{{A: "oops", B: "3", Key: "x"}},
+			expectedErr: "cant read from conn remote server answered 400 Bad Request: not correct format",
+		},
+		{
+			name:        "deadline",
+			pairs:       []*models.Pair{{A: "1", B: "1", Key: "x"}},
+			timeout:     100 * time.Millisecond,
+			expectedErr: "context deadline exceeded",
+		},
+	}
+
+	serv := NewTService(nil, NewMetricsConnector(NewH2CConnector(strings.TrimPrefix(srv.URL, "http://"))))
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+
+			res, err := serv.MulStringVal(ctx, tc.pairs)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, res)
+		})
+	}
+}
+
+func TestH2CConnector_Ping(t *testing.T) {
+	srv := newH2CServer(t, map[string]string{
+		pingMsg: "PONG\r\nversion=1.2.0\r\nuptime=90\r\nload=3\r\n\r\n ",
+	})
+
+	c := NewMetricsConnector(NewH2CConnector(strings.TrimPrefix(srv.URL, "http://")))
+
+	h, err := c.Ping(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &RemoteHealth{Version: "1.2.0", Uptime: 90 * time.Second, Load: 3}, h)
+
+	assert.NoError(t, Reachable(c)(context.Background()))
+	assert.Error(t, Reachable(NewH2CConnector("localhost:1"))(context.Background()))
+}
+
+func TestH2CConn_Write(t *testing.T) {
+	srv := newH2CServer(t, map[string]string{"2,3\r\n\r\n ": "6\r\n\r\n "})
+
+	conn, err := NewH2CConnector(strings.TrimPrefix(srv.URL, "http://")).Connect(context.Background())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = io.WriteString(conn, "2,3\r\n\r\n ")
+	assert.NoError(t, err)
+
+	bs, err := io.ReadAll(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, "6\r\n\r\n ", string(bs))
+
+	_, err = io.WriteString(conn, "2,3\r\n\r\n ")
+	assert.ErrorIs(t, err, ErrFrameSent)
+}
+
+func TestH2CConn_SetDeadline(t *testing.T) {
+	srv := newH2CServer(t, map[string]string{"2,3\r\n\r\n ": "6\r\n\r\n "})
+
+	conn, err := NewH2CConnector(strings.TrimPrefix(srv.URL, "http://")).Connect(context.Background())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// later deadline replaces the passed one
+	setDeadline(conn, time.Now().Add(-time.Second))
+	setDeadline(conn, time.Now().Add(time.Minute))
+
+	_, err = io.WriteString(conn, "2,3\r\n\r\n ")
+	assert.NoError(t, err)
+
+	bs, err := io.ReadAll(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, "6\r\n\r\n ", string(bs))
+}
+
+func TestH2CConn_Timeout(t *testing.T) {
+	srv := newH2CServer(t, nil)
+
+	c := NewH2CConnector(strings.TrimPrefix(srv.URL, "http://"))
+	c.client.Timeout = 100 * time.Millisecond
+
+	conn, err := c.Connect(context.Background())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = io.WriteString(conn, "2,3\r\n\r\n ")
+	assert.NoError(t, err)
+
+	_, err = io.ReadAll(conn)
+	assert.Error(t, err)
+}
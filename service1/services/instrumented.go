@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"io"
+	"service1/database"
+	"service1/metrics"
+	"service1/models"
+	"service1/tracing"
+	"sync"
+	"time"
+)
+
+// InstrumentedService observes latency of every Service method and traces it
+// as a child span of request.
+type InstrumentedService struct {
+	Service
+}
+
+var _ Service = (*InstrumentedService)(nil)
+
+// NewInstrumentedService .
+func NewInstrumentedService(s Service) *InstrumentedService {
+	return &InstrumentedService{Service: s}
+}
+
+// instrument starts span of method. Returned func is deferred with pointer to
+// named error, so error returned by method is seen.
+func instrument(ctx context.Context, method string) (context.Context, func(*error)) {
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "TService."+method)
+
+	return ctx, func(err *error) {
+		metrics.ServiceDuration.WithLabelValues(method, metrics.Result(*err)).Observe(time.Since(start).Seconds())
+		tracing.End(span, *err)
+	}
+}
+
+// IncrementBy .
+func (s *InstrumentedService) IncrementBy(ctx context.Context, msg *models.IncrMsgIn) (_ *models.IncrMsgOut, err error) {
+	ctx, end := instrument(ctx, "IncrementBy")
+	defer end(&err)
+	return s.Service.IncrementBy(ctx, msg)
+}
+
+// IncrementMany .
+func (s *InstrumentedService) IncrementMany(ctx context.Context, batch models.IncrBatchIn) (_ map[string]int64, err error) {
+	ctx, end := instrument(ctx, "IncrementMany")
+	defer end(&err)
+	return s.Service.IncrementMany(ctx, batch)
+}
+
+// Get .
+func (s *InstrumentedService) Get(ctx context.Context, key string) (_ map[string]int64, err error) {
+	ctx, end := instrument(ctx, "Get")
+	defer end(&err)
+	return s.Service.Get(ctx, key)
+}
+
+// GetMany .
+func (s *InstrumentedService) GetMany(ctx context.Context, keys []string) (_ map[string]int64, err error) {
+	ctx, end := instrument(ctx, "GetMany")
+	defer end(&err)
+	return s.Service.GetMany(ctx, keys)
+}
+
+// Set .
+func (s *InstrumentedService) Set(ctx context.Context, key string, val int64) (_ map[string]int64, err error) {
+	ctx, end := instrument(ctx, "Set")
+	defer end(&err)
+	return s.Service.Set(ctx, key, val)
+}
+
+// Delete .
+func (s *InstrumentedService) Delete(ctx context.Context, key string) (err error) {
+	ctx, end := instrument(ctx, "Delete")
+	defer end(&err)
+	return s.Service.Delete(ctx, key)
+}
+
+// Subscribe observes only subscription setup, not the stream.
+func (s *InstrumentedService) Subscribe(ctx context.Context, key string, lastID int64) (_ <-chan *database.Event, err error) {
+	ctx, end := instrument(ctx, "Subscribe")
+	defer end(&err)
+	return s.Service.Subscribe(ctx, key, lastID)
+}
+
+// History .
+func (s *InstrumentedService) History(ctx context.Context, q *models.HistoryQuery) (_ *models.HistoryMsgOut, err error) {
+	ctx, end := instrument(ctx, "History")
+	defer end(&err)
+	return s.Service.History(ctx, q)
+}
+
+// HashString .
+func (s *InstrumentedService) HashString(ctx context.Context, str, key string) string {
+	var err error
+	ctx, end := instrument(ctx, "HashString")
+	defer end(&err)
+	return s.Service.HashString(ctx, str, key)
+}
+
+// MulStringVal .
+func (s *InstrumentedService) MulStringVal(ctx context.Context, pairs []*models.Pair) (_ map[string]int, err error) {
+	ctx, end := instrument(ctx, "MulStringVal")
+	defer end(&err)
+	return s.Service.MulStringVal(ctx, pairs)
+}
+
+// MetricsConnector observes dial, read and write latency of connections to
+// remote server and counts open ones. Connections are not pooled, every
+// request dials a new one.
+type MetricsConnector struct {
+	RemoteConnector
+}
+
+// NewMetricsConnector .
+func NewMetricsConnector(c RemoteConnector) *MetricsConnector {
+	return &MetricsConnector{RemoteConnector: c}
+}
+
+// Connect .
+func (c *MetricsConnector) Connect(ctx context.Context) (io.ReadWriteCloser, error) {
+	start := time.Now()
+	conn, err := c.RemoteConnector.Connect(ctx)
+	metrics.RemoteDuration.WithLabelValues("dial", metrics.Result(err)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.RemoteConns.Inc()
+	return &metricsConn{ReadWriteCloser: conn}, nil
+}
+
+// Ping pings with wrapped connector when it can, so health checks are
+// observed too.
+func (c *MetricsConnector) Ping(ctx context.Context) (*RemoteHealth, error) {
+	if _, ok := c.RemoteConnector.(Pinger); !ok {
+		return nil, ErrPingUnsupported
+	}
+
+	return ping(ctx, c)
+}
+
+type metricsConn struct {
+	io.ReadWriteCloser
+	once sync.Once
+}
+
+func (c *metricsConn) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := c.ReadWriteCloser.Read(p)
+	metrics.RemoteDuration.WithLabelValues("read", readResult(err)).Observe(time.Since(start).Seconds())
+	return n, err
+}
+
+func (c *metricsConn) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := c.ReadWriteCloser.Write(p)
+	metrics.RemoteDuration.WithLabelValues("write", metrics.Result(err)).Observe(time.Since(start).Seconds())
+	return n, err
+}
+
+func (c *metricsConn) Close() error {
+	c.once.Do(metrics.RemoteConns.Dec)
+	return c.ReadWriteCloser.Close()
+}
+
+// readResult treats EOF as success, remote server closes connection after
+// the answer.
+func readResult(err error) string {
+	if err == io.EOF {
+		return metrics.Result(nil)
+	}
+
+	return metrics.Result(err)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"service1/tracing"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	pingMsg = "PING" + pairsep + pairsep + string(eof)
+	pongMsg = "PONG"
+)
+
+// ErrPingUnsupported .
+var ErrPingUnsupported = errors.New("connector cant ping remote server")
+
+// RemoteHealth is remote server answer to ping.
+type RemoteHealth struct {
+	Version string
+	Uptime  time.Duration
+	// Load is number of connections remote server is serving.
+	Load int64
+}
+
+// Pinger is implemented by connectors able to health check remote server.
+type Pinger interface {
+	Ping(context.Context) (*RemoteHealth, error)
+}
+
+// Ping sends ping frame to remote server and parses its answer.
+func (c *TCPConnector) Ping(ctx context.Context) (*RemoteHealth, error) {
+	return ping(ctx, c)
+}
+
+func ping(ctx context.Context, c RemoteConnector) (_ *RemoteHealth, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "remote.ping", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	if d, ok := ctx.Deadline(); ok {
+		setDeadline(conn, d)
+	}
+
+	if _, err = conn.Write([]byte(marshalHeader(ctx) + pingMsg)); err != nil {
+		return nil, fmt.Errorf("cant write to conn %w", err)
+	}
+
+	bs, err := ioutil.ReadAll(conn)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("cant read from conn %w", err)
+	}
+
+	return unmarshalPong(checkHeader(ctx, string(bs)))
+}
+
+// setDeadline sets deadline of net connection, possibly wrapped by metrics.
+func setDeadline(conn io.ReadWriteCloser, d time.Time) {
+	if mc, ok := conn.(*metricsConn); ok {
+		conn = mc.ReadWriteCloser
+	}
+
+	if dc, ok := conn.(interface{ SetDeadline(time.Time) error }); ok {
+		dc.SetDeadline(d)
+	}
+}
+
+func unmarshalPong(str string) (*RemoteHealth, error) {
+	str = strings.TrimSuffix(str, pairsep+pairsep+string(eof))
+
+	strs := strings.Split(str, pairsep)
+	if strs[0] != pongMsg {
+		return nil, ErrNotCorrectFormat
+	}
+
+	h := &RemoteHealth{}
+	for _, s := range strs[1:] {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 {
+			return nil, ErrNotCorrectFormat
+		}
+
+		switch kv[0] {
+		case "version":
+			h.Version = kv[1]
+		case "uptime":
+			sec, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("not correct format %w", err)
+			}
+			h.Uptime = time.Duration(sec) * time.Second
+		case "load":
+			n, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("not correct format %w", err)
+			}
+			h.Load = n
+		}
+	}
+
+	return h, nil
+}
+
+// Reachable returns health check passing when at least one of remote servers
+// answers ping, servers behind connectors which cant ping are only connected.
+func Reachable(connectors ...RemoteConnector) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var err error
+		for _, c := range connectors {
+			if err = reach(ctx, c); err == nil {
+				return nil
+			}
+		}
+
+		return err
+	}
+}
+
+func reach(ctx context.Context, c RemoteConnector) error {
+	if p, ok := c.(Pinger); ok {
+		_, err := p.Ping(ctx)
+		if !errors.Is(err, ErrPingUnsupported) {
+			return err
+		}
+	}
+
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
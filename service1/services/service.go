@@ -11,15 +11,25 @@ import (
 	"io/ioutil"
 	"net"
 	"service1/database"
+	"service1/logger"
 	"service1/models"
+	"service1/tracing"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	pairsep  = "\r\n"
 	digitsep = ","
 	eof      = ' '
+
+	headerPrefix    = "@"
+	requestIDHeader = "request-id"
 )
 
 // ErrNotCorrectFormat .
@@ -27,7 +37,14 @@ var ErrNotCorrectFormat = errors.New("not correct format")
 
 // Service .
 type Service interface {
-	IncrementBy(context.Context, string, int64) (map[string]int64, error)
+	IncrementBy(context.Context, *models.IncrMsgIn) (*models.IncrMsgOut, error)
+	IncrementMany(context.Context, models.IncrBatchIn) (map[string]int64, error)
+	Get(context.Context, string) (map[string]int64, error)
+	GetMany(context.Context, []string) (map[string]int64, error)
+	Set(context.Context, string, int64) (map[string]int64, error)
+	Delete(context.Context, string) error
+	Subscribe(context.Context, string, int64) (<-chan *database.Event, error)
+	History(context.Context, *models.HistoryQuery) (*models.HistoryMsgOut, error)
 	HashString(context.Context, string, string) string
 	MulStringVal(context.Context, []*models.Pair) (map[string]int, error)
 }
@@ -76,22 +93,153 @@ func NewTService(db database.DB, connector RemoteConnector) *TService {
 }
 
 // IncrementBy .
-func (s *TService) IncrementBy(ctx context.Context, key string, val int64) (map[string]int64, error) {
-	m := make(map[string]int64)
+func (s *TService) IncrementBy(ctx context.Context, msg *models.IncrMsgIn) (*models.IncrMsgOut, error) {
+	opts := database.IncrOpts{
+		TTL:         time.Duration(msg.TTL) * time.Millisecond,
+		TTLOnCreate: msg.TTLOnCreate,
+		Min:         msg.Min,
+		Max:         msg.Max,
+	}
 
-	i, err := s.DB.IncrementBy(ctx, key, val)
+	c, err := s.DB.IncrementBy(ctx, msg.Key, msg.Val, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	m[key] = i
-	return m, nil
+	out := &models.IncrMsgOut{Key: msg.Key, Res: c.Val, TTL: -1}
+	if c.TTL >= 0 {
+		out.TTL = c.TTL.Milliseconds()
+	}
+
+	return out, nil
+}
+
+// IncrementMany .
+func (s *TService) IncrementMany(ctx context.Context, batch models.IncrBatchIn) (map[string]int64, error) {
+	vals := make(map[string]int64, len(batch))
+	for _, item := range batch {
+		vals[item.Key] = item.Val
+	}
+
+	return s.DB.IncrementMany(ctx, vals)
+}
+
+// Get .
+func (s *TService) Get(ctx context.Context, key string) (map[string]int64, error) {
+	i, err := s.DB.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]int64{key: i}, nil
+}
+
+// GetMany .
+func (s *TService) GetMany(ctx context.Context, keys []string) (map[string]int64, error) {
+	return s.DB.GetMany(ctx, keys)
+}
+
+// Set .
+func (s *TService) Set(ctx context.Context, key string, val int64) (map[string]int64, error) {
+	if err := s.DB.Set(ctx, key, val); err != nil {
+		return nil, err
+	}
+
+	return map[string]int64{key: val}, nil
+}
+
+// Delete .
+func (s *TService) Delete(ctx context.Context, key string) error {
+	return s.DB.Delete(ctx, key)
+}
+
+// Subscribe streams changes of key. Subscriber without last event id first
+// gets current value as snapshot event, reconnected one gets missed events.
+func (s *TService) Subscribe(ctx context.Context, key string, lastID int64) (<-chan *database.Event, error) {
+	live, err := s.DB.Subscribe(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var first []*database.Event
+	if lastID > 0 {
+		first, err = s.DB.Events(ctx, key, lastID)
+	} else {
+		var val int64
+		val, err = s.DB.Get(ctx, key)
+		if err == nil {
+			first = []*database.Event{{Op: database.OpSnapshot, Key: key, Val: val}}
+		}
+		if errors.Is(err, database.ErrNotFound) {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *database.Event)
+	go func() {
+		defer close(ch)
+
+		send := func(ev *database.Event) bool {
+			select {
+			case ch <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, ev := range first {
+			if !send(ev) {
+				return
+			}
+			if ev.ID > lastID {
+				lastID = ev.ID
+			}
+		}
+
+		for ev := range live {
+			// already replayed from log
+			if ev.ID <= lastID {
+				continue
+			}
+
+			if !send(ev) {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// History .
+func (s *TService) History(ctx context.Context, q *models.HistoryQuery) (*models.HistoryMsgOut, error) {
+	points, err := s.DB.History(ctx, q.Key, q.From, q.To, q.Step)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &models.HistoryMsgOut{
+		Key:    q.Key,
+		Step:   q.Step.String(),
+		Points: make([]*models.Point, len(points)),
+	}
+
+	for i, p := range points {
+		out.Points[i] = &models.Point{Time: p.Time, Delta: p.Delta}
+	}
+
+	return out, nil
 }
 
 // HashString .
 func (s *TService) HashString(ctx context.Context, str, key string) string {
 
-	fmt.Printf("str & key are %v, %v\n", str, key)
+	logger.FromContext(ctx).Debug("hashing string", "str", str, "hmac_key", logger.Secret(key))
 
 	h := hmac.New(sha512.New, []byte(key))
 
@@ -104,14 +252,19 @@ func (s *TService) HashString(ctx context.Context, str, key string) string {
 }
 
 // MulStringVal .
-func (s *TService) MulStringVal(ctx context.Context, pairs []*models.Pair) (map[string]int, error) {
+func (s *TService) MulStringVal(ctx context.Context, pairs []*models.Pair) (_ map[string]int, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "remote.mul",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int("pairs", len(pairs))),
+	)
+	defer func() { tracing.End(span, err) }()
 
 	keys := make([]string, len(pairs))
 	for i, v := range pairs {
 		keys[i] = v.Key
 	}
 
-	str := MarshalMsg(pairs)
+	str := marshalHeader(ctx) + MarshalMsg(pairs)
 
 	conn, err := s.Connector.Connect(ctx)
 	if err != nil {
@@ -120,6 +273,11 @@ func (s *TService) MulStringVal(ctx context.Context, pairs []*models.Pair) (map[
 
 	defer conn.Close()
 
+	// deadline of caller, like grpc one, bounds the whole exchange
+	if d, ok := ctx.Deadline(); ok {
+		setDeadline(conn, d)
+	}
+
 	if _, err = conn.Write([]byte(str)); err != nil {
 		return nil, fmt.Errorf("cant write to conn %w", err)
 	}
@@ -129,7 +287,9 @@ func (s *TService) MulStringVal(ctx context.Context, pairs []*models.Pair) (map[
 		return nil, fmt.Errorf("cant read from conn %w", err)
 	}
 
-	m, err := UnmarshalMsg(keys, string(bs))
+	body := checkHeader(ctx, string(bs))
+
+	m, err := UnmarshalMsg(keys, body)
 	if err != nil {
 		return nil, err
 	}
@@ -137,6 +297,61 @@ func (s *TService) MulStringVal(ctx context.Context, pairs []*models.Pair) (map[
 	return m, nil
 }
 
+// marshalHeader returns frame header lines with values carried by ctx, request
+// id and W3C trace context. Remote server echoes them back.
+func marshalHeader(ctx context.Context) string {
+	hdr := tracing.Inject(ctx)
+	if id := logger.RequestID(ctx); id != "" {
+		hdr[requestIDHeader] = id
+	}
+
+	keys := make([]string, 0, len(hdr))
+	for k := range hdr {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, k := range keys {
+		builder.WriteString(headerPrefix + k + "=" + hdr[k] + pairsep)
+	}
+
+	return builder.String()
+}
+
+// splitHeader returns header values of frame and the rest of it.
+func splitHeader(str string) (map[string]string, string) {
+	hdr := make(map[string]string)
+
+	for strings.HasPrefix(str, headerPrefix) {
+		i := strings.Index(str, pairsep)
+		if i < 0 {
+			break
+		}
+
+		kv := strings.SplitN(str[len(headerPrefix):i], "=", 2)
+		if len(kv) == 2 {
+			hdr[kv[0]] = kv[1]
+		}
+
+		str = str[i+len(pairsep):]
+	}
+
+	return hdr, str
+}
+
+// checkHeader strips header of answer and reports request id mismatch, which
+// means answer belongs to another request.
+func checkHeader(ctx context.Context, str string) string {
+	hdr, body := splitHeader(str)
+
+	if id := logger.RequestID(ctx); id != "" && hdr[requestIDHeader] != id {
+		logger.FromContext(ctx).Warn("remote server did not echo request id", "echoed", hdr[requestIDHeader])
+	}
+
+	return body
+}
+
 // MarshalMsg .
 func MarshalMsg(pairs []*models.Pair) string {
 	var builder strings.Builder
@@ -165,7 +380,7 @@ func UnmarshalMsg(keys []string, str string) (map[string]int, error) {
 	strs := strings.Split(str, "\r\n")
 
 	if len(strs) != len(keys) {
-		return nil, errors.New("not correct format")
+		return nil, ErrNotCorrectFormat
 	}
 
 	for i, s := range strs {
@@ -10,14 +10,23 @@ import (
 	"io/ioutil"
 	"net"
 	"service1/database"
+	"service1/logger"
+	"service1/metrics"
 	"service1/models"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 
-	"github.com/alicebob/miniredis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestServiceMulStringVal_RemoteUnavail(t *testing.T) {
@@ -126,7 +135,7 @@ func TestUnmarshalMsg(t *testing.T) {
 
 			m, err := UnmarshalMsg(tc.keys, tc.str)
 
-			if err != tc.err && !errors.As(err, &tc.err) {
+			if err != tc.err && !errors.Is(err, tc.err) {
 				t.Fatalf("expecting err %v, %T, got %v, %T", err, err, tc.err, tc.err)
 			}
 
@@ -203,14 +212,14 @@ func TestIncrementBy(t *testing.T) {
 		name string
 		key  string
 		val  int64
-		res  map[string]int64
+		res  *models.IncrMsgOut
 		err  error
 	}{
 		{
 			name: "ok",
 			key:  "test",
 			val:  12,
-			res:  map[string]int64{"test": 12},
+			res:  &models.IncrMsgOut{Key: "test", Res: 12, TTL: -1},
 			err:  nil,
 		},
 	}
@@ -233,10 +242,242 @@ func TestIncrementBy(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 
-			m, err := srv.IncrementBy(context.Background(), tc.key, tc.val)
+			m, err := srv.IncrementBy(context.Background(), &models.IncrMsgIn{Key: tc.key, Val: tc.val})
 			assert.Equal(t, tc.res, m)
 
 			assert.NoError(t, err)
 		})
 	}
 }
+
+func TestIncrementBy_TTL(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisServer.Addr(),
+	})
+
+	db := database.NewDB(redisClient)
+	defer db.Stop()
+
+	srv := NewTService(db, nil)
+	ctx := context.Background()
+
+	always := &models.IncrMsgIn{Key: "always", Val: 1, TTL: 60000}
+	oncreate := &models.IncrMsgIn{Key: "oncreate", Val: 1, TTL: 60000, TTLOnCreate: true}
+
+	m, err := srv.IncrementBy(ctx, always)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.IncrMsgOut{Key: "always", Res: 1, TTL: 60000}, m)
+
+	m, err = srv.IncrementBy(ctx, oncreate)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.IncrMsgOut{Key: "oncreate", Res: 1, TTL: 60000}, m)
+
+	redisServer.FastForward(10 * time.Second)
+
+	m, err = srv.IncrementBy(ctx, always)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.IncrMsgOut{Key: "always", Res: 2, TTL: 60000}, m)
+
+	m, err = srv.IncrementBy(ctx, oncreate)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.IncrMsgOut{Key: "oncreate", Res: 2, TTL: 50000}, m)
+
+	redisServer.FastForward(time.Minute)
+
+	_, err = srv.Get(ctx, "oncreate")
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestIncrementBy_BoundsBeyondFloat(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	db := database.NewDB(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	defer db.Stop()
+
+	srv := NewTService(db, nil)
+	ctx := context.Background()
+
+	// 2^53+1 has no exact float64, it rounds so that +1 looks like no change
+	max := int64(1<<53 + 1)
+	_, err = srv.Set(ctx, "big", max)
+	assert.NoError(t, err)
+
+	_, err = srv.IncrementBy(ctx, &models.IncrMsgIn{Key: "big", Val: 1, Max: &max})
+	var berr *database.BoundError
+	if assert.ErrorAs(t, err, &berr) {
+		assert.Equal(t, max, berr.Val)
+	}
+
+	min := max - 1
+	m, err := srv.IncrementBy(ctx, &models.IncrMsgIn{Key: "big", Val: -1, Min: &min})
+	assert.NoError(t, err)
+	assert.Equal(t, &models.IncrMsgOut{Key: "big", Res: min, TTL: -1}, m)
+
+	assert.NoError(t, redisServer.Set("text", "oops"))
+	_, err = srv.IncrementBy(ctx, &models.IncrMsgIn{Key: "text", Val: 1, Max: &max})
+	assert.ErrorIs(t, err, database.ErrNotInteger)
+}
+
+func TestGetSetDelete(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisServer.Addr(),
+	})
+
+	db := database.NewDB(redisClient)
+	defer db.Stop()
+
+	srv := NewTService(db, nil)
+	ctx := context.Background()
+
+	_, err = srv.Get(ctx, "test")
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	m, err := srv.Set(ctx, "test", 7)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"test": 7}, m)
+
+	m, err = srv.Get(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"test": 7}, m)
+
+	m, err = srv.GetMany(ctx, []string{"test", "missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"test": 7}, m)
+
+	assert.NoError(t, srv.Delete(ctx, "test"))
+	assert.ErrorIs(t, srv.Delete(ctx, "test"), database.ErrNotFound)
+}
+
+func TestTCPConnectorPing(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		bs, err := bufio.NewReader(conn).ReadBytes(eof)
+		assert.NoError(t, err)
+		assert.Equal(t, pingMsg, string(bs))
+
+		conn.Write([]byte("PONG\r\nversion=1.2.0\r\nuptime=90\r\nload=3\r\n\r\n "))
+	}()
+
+	h, err := NewTCPConnector(l.Addr().String()).Ping(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &RemoteHealth{Version: "1.2.0", Uptime: 90 * time.Second, Load: 3}, h)
+}
+
+func TestReachable(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	// server accepting connections but not speaking the protocol
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	down := NewTCPConnector("localhost:1")
+	silent := NewTCPConnector(l.Addr().String())
+	// fake connector cant ping, so connecting is enough
+	up := NewFakeConnector(net.Pipe())
+
+	assert.Error(t, Reachable(down)(context.Background()))
+	assert.Error(t, Reachable(silent)(context.Background()))
+	assert.NoError(t, Reachable(down, up)(context.Background()))
+}
+
+func TestMetricsConnector(t *testing.T) {
+	fake := NewFakeConnector(net.Pipe())
+	conn := NewMetricsConnector(fake)
+
+	go func() {
+		bufio.NewReader(fake.Remote).ReadBytes(eof)
+		fake.Remote.Write([]byte("516\r\n\r\n "))
+		fake.Remote.Close()
+	}()
+
+	open := testutil.ToFloat64(metrics.RemoteConns)
+
+	srv := NewTService(nil, conn)
+	res, err := srv.MulStringVal(context.Background(), []*models.Pair{{A: "12", B: "43", Key: "x"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"x": 516}, res)
+
+	assert.Equal(t, open, testutil.ToFloat64(metrics.RemoteConns))
+
+	_, err = conn.Ping(context.Background())
+	assert.ErrorIs(t, err, ErrPingUnsupported)
+}
+
+func TestMulStringVal_RequestID(t *testing.T) {
+	fake := NewFakeConnector(net.Pipe())
+	serv := NewTService(nil, fake)
+
+	go func() {
+		bs, err := bufio.NewReader(fake.Remote).ReadBytes(eof)
+		assert.NoError(t, err)
+		assert.Equal(t, "@request-id=abc\r\n12,43\r\n\r\n ", string(bs))
+
+		fake.Remote.Write([]byte("@request-id=abc\r\n516\r\n\r\n "))
+		fake.Remote.Close()
+	}()
+
+	ctx := logger.WithRequestID(context.Background(), "abc")
+	res, err := serv.MulStringVal(ctx, []*models.Pair{{A: "12", B: "43", Key: "x"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"x": 516}, res)
+}
+
+func TestMulStringVal_Tracing(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	fake := NewFakeConnector(net.Pipe())
+	serv := NewInstrumentedService(NewTService(nil, fake))
+
+	frames := make(chan string, 1)
+	go func() {
+		bs, err := bufio.NewReader(fake.Remote).ReadBytes(eof)
+		assert.NoError(t, err)
+		frames <- string(bs)
+
+		fake.Remote.Write([]byte("516\r\n\r\n "))
+		fake.Remote.Close()
+	}()
+
+	_, err := serv.MulStringVal(context.Background(), []*models.Pair{{A: "12", B: "43", Key: "x"}})
+	assert.NoError(t, err)
+
+	spans := exp.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "remote.mul", spans[0].Name)
+	assert.Equal(t, "TService.MulStringVal", spans[1].Name)
+	assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+
+	remote := spans[0].SpanContext
+	assert.Equal(t, "@traceparent=00-"+remote.TraceID().String()+"-"+remote.SpanID().String()+"-01\r\n12,43\r\n\r\n ", <-frames)
+}
@@ -0,0 +1,113 @@
+// Package tracing configures opentelemetry tracer provider of the service
+// and W3C trace context propagation. service2 sets it up the same way.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const name = "service1"
+
+// Exporters.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// ErrUnknownExporter .
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+// Config .
+type Config struct {
+	// Exporter is none, stdout or otlp.
+	Exporter string
+	// Endpoint is host:port of otlp/http collector.
+	Endpoint string
+	// Service is name spans are exported with, service1 when empty.
+	Service string
+	// Output is written by stdout exporter, stderr when nil so spans do not
+	// mix with logs written to stdout.
+	Output io.Writer
+}
+
+// Init sets global tracer provider exporting spans as configured and trace
+// context propagator. Returned func flushes and stops the provider.
+func Init(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var exp sdktrace.SpanExporter
+	var err error
+
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		w := cfg.Output
+		if w == nil {
+			w = os.Stderr
+		}
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterOTLP:
+		exp, err = otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpoint(cfg.Endpoint),
+			otlptracehttp.WithInsecure(),
+		)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownExporter, cfg.Exporter)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cant create trace exporter %w", err)
+	}
+
+	service := cfg.Service
+	if service == "" {
+		service = name
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// Tracer returns tracer of global provider, so spans started before Init
+// are no-ops.
+func Tracer() trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Inject returns trace context of ctx as header values.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// End records err, if any, as span status and ends span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInit(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  Config
+		err  error
+	}{
+		{name: "none", cfg: Config{Exporter: ExporterNone}},
+		{name: "stdout", cfg: Config{Exporter: ExporterStdout}},
+		{name: "otlp", cfg: Config{Exporter: ExporterOTLP, Endpoint: "localhost:4318"}},
+		{name: "unknown", cfg: Config{Exporter: "zipkin"}, err: ErrUnknownExporter},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shutdown, err := Init(context.Background(), tc.cfg)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
+
+func TestInit_Output(t *testing.T) {
+	var buf bytes.Buffer
+
+	shutdown, err := Init(context.Background(), Config{Exporter: ExporterStdout, Service: "service2", Output: &buf})
+	assert.NoError(t, err)
+
+	_, span := Tracer().Start(context.Background(), "test")
+	End(span, nil)
+
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, buf.String(), `"Name":"test"`)
+	assert.Contains(t, buf.String(), `"Value":"service2"`)
+}
+
+func TestInject(t *testing.T) {
+	_, err := Init(context.Background(), Config{})
+	assert.NoError(t, err)
+
+	assert.Empty(t, Inject(context.Background()))
+
+	tid, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	sid, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: sid, TraceFlags: trace.FlagsSampled})
+
+	hdr := Inject(trace.ContextWithSpanContext(context.Background(), sc))
+	assert.Equal(t, map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, hdr)
+}
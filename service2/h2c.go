@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// mulPath serves frames as http request bodies, answers are the same frames
+// the tcp server writes.
+const mulPath = "/multiply"
+
+// mulHandler handles body of request like a tcp connection. Frames which
+// cant be read or parsed are answered with 400 instead of closed connection.
+func mulHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	// handleConn does not touch conn after error is sent or channel closed
+	if err, ok := <-handleConn(&httpConn{Reader: r.Body, Writer: w}); ok {
+		slog.Warn("cant handle request", "request_id", requestID(err), "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// httpConn is a connection of request body and response.
+type httpConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (*httpConn) Close() error {
+	return nil
+}
+
+// withH2C serves handler over http/2 without tls too, http/1 requests are
+// served as before.
+func withH2C(h http.Handler) http.Handler {
+	return h2c.NewHandler(h, &http2.Server{})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+func TestMulHandler(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		req          string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "ok",
+			method:       http.MethodPost,
+			req:          "12,43\r\n11,3\r\n\r\n ",
+			expectedCode: http.StatusOK,
+			expectedBody: "516\r\n33\r\n\r\n ",
+		},
+		{
+			name:         "request id echoed",
+			method:       http.MethodPost,
+			req:          "@request-id=abc\r\n2,3\r\n\r\n ",
+			expectedCode: http.StatusOK,
+			expectedBody: "@request-id=abc\r\n6\r\n\r\n ",
+		},
+		{
+			name:         "not correct format",
+			method:       http.MethodPost,
+			req:          "12,43\r\noops,3\r\n\r\n ",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "not correct format",
+		},
+		{
+			name:         "incomplete frame",
+			method:       http.MethodPost,
+			req:          "12,43",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "EOF\n",
+		},
+		{
+			name:         "wrong method",
+			method:       http.MethodGet,
+			expectedCode: http.StatusMethodNotAllowed,
+			expectedBody: "Method Not Allowed\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, mulPath, strings.NewReader(tc.req))
+
+			mulHandler(rec, req)
+
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedBody)
+		})
+	}
+}
+
+func TestMulHandler_H2C(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(mulPath, mulHandler)
+
+	srv := httptest.NewServer(withH2C(mux))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+
+	res, err := client.Post(srv.URL+mulPath, "text/plain", bytes.NewBufferString("PING\r\n\r\n "))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer res.Body.Close()
+
+	bs, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, res.ProtoMajor)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Regexp(t, `^PONG\r\nversion=dev\r\n`, string(bs))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// Frame header lines precede message, like "@request-id=abc\r\n". They are
+// echoed back before the answer.
+const (
+	headerPrefix    = "@"
+	requestIDHeader = "request-id"
+)
+
+// splitHeader returns header values of frame, raw header lines and the rest
+// of frame.
+func splitHeader(str string) (map[string]string, string, string) {
+	hdr := make(map[string]string)
+	rest := str
+
+	for strings.HasPrefix(rest, headerPrefix) {
+		i := strings.Index(rest, pairsep)
+		if i < 0 {
+			break
+		}
+
+		kv := strings.SplitN(rest[len(headerPrefix):i], "=", 2)
+		if len(kv) == 2 {
+			hdr[kv[0]] = kv[1]
+		}
+
+		rest = rest[i+len(pairsep):]
+	}
+
+	return hdr, str[:len(str)-len(rest)], rest
+}
+
+// reqError is an error of message sent with request id.
+type reqError struct {
+	id  string
+	err error
+}
+
+func (e *reqError) Error() string {
+	return "request " + e.id + ": " + e.err.Error()
+}
+
+func (e *reqError) Unwrap() error {
+	return e.err
+}
+
+// withRequestID wraps err with request id, if there is one.
+func withRequestID(id string, err error) error {
+	if id == "" {
+		return err
+	}
+
+	return &reqError{id: id, err: err}
+}
+
+// requestID returns request id err was wrapped with.
+func requestID(err error) string {
+	var rerr *reqError
+	if errors.As(err, &rerr) {
+		return rerr.id
+	}
+
+	return ""
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// pingMsg is a health check frame, it is answered with pong frame of
+// key=value lines instead of multiplication results.
+const (
+	pingMsg = "PING" + pairsep + eof
+	pongMsg = "PONG"
+)
+
+// proc holds process wide stats reported by health checks.
+var proc = &procStats{start: time.Now()}
+
+type procStats struct {
+	start time.Time
+	conns int64
+}
+
+type health struct {
+	Version string `json:"version"`
+	// Uptime in seconds.
+	Uptime int64 `json:"uptime"`
+	// Load is number of connections being served.
+	Load int64 `json:"load"`
+}
+
+// track counts connection as served until returned func is called.
+func (p *procStats) track() func() {
+	atomic.AddInt64(&p.conns, 1)
+	return func() { atomic.AddInt64(&p.conns, -1) }
+}
+
+func (p *procStats) health() *health {
+	return &health{
+		Version: version,
+		Uptime:  int64(time.Since(p.start).Seconds()),
+		Load:    atomic.LoadInt64(&p.conns),
+	}
+}
+
+func marshalPong(h *health) string {
+	var builder strings.Builder
+
+	builder.WriteString(pongMsg + pairsep)
+	builder.WriteString("version=" + h.Version + pairsep)
+	builder.WriteString("uptime=" + strconv.FormatInt(h.Uptime, 10) + pairsep)
+	builder.WriteString("load=" + strconv.FormatInt(h.Load, 10) + pairsep)
+	builder.WriteString(eof)
+
+	return builder.String()
+}
+
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(proc.health()); err != nil {
+		slog.Warn("cant write health", "err", err)
+	}
+}
@@ -1,8 +1,16 @@
 package main
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"flag"
+	"log/slog"
+	"net"
+	"net/http"
+	"service1/logger"
+	"service1/tracing"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
@@ -18,15 +26,97 @@ const (
 // ErrNotCorrectFormat .
 var ErrNotCorrectFormat = errors.New("not correct format")
 
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+var (
+	healthport  string
+	metricsport string
+	h2cport     string
+	logcfg      logger.Config
+	traceexp    string
+	otlpaddr    string
+)
+
+func init() {
+	flag.StringVar(&healthport, "health-port", "", "provide http health port, disabled when empty")
+	flag.StringVar(&metricsport, "metrics-port", "", "provide http metrics port, disabled when empty, may be the same as health port")
+	flag.StringVar(&h2cport, "h2c-port", "", "provide http/2 without tls multiply port, disabled when empty, may be the same as health port")
+	flag.StringVar(&logcfg.Level, "log-level", "info", "provide log level, debug, info, warn or error")
+	flag.StringVar(&logcfg.Format, "log-format", "json", "provide log format, json or text")
+	flag.StringVar(&logcfg.Output, "log-output", "stdout", "provide log output, stdout, stderr or file path")
+	flag.StringVar(&traceexp, "trace-exporter", "none", "provide trace exporter, none, stdout or otlp, stdout one writes to stderr")
+	flag.StringVar(&otlpaddr, "otlp-endpoint", "localhost:4318", "provide otlp/http collector address")
+}
+
 func main() {
 
+	flag.Parse()
+
+	log, closer, err := logger.New(logcfg)
+	if err != nil {
+		panic(err)
+	}
+	defer closer.Close()
+
+	slog.SetDefault(log)
+
+	shutdown, err := tracing.Init(context.Background(), tracing.Config{
+		Exporter: traceexp,
+		Endpoint: otlpaddr,
+		Service:  "service2",
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := shutdown(context.Background()); err != nil {
+			log.Error("cant flush spans", "err", err)
+		}
+	}()
+
+	for p, mux := range httpMuxes() {
+		go func(addr string, mux *http.ServeMux) {
+			log.Info("http server started", "addr", addr)
+			if err := http.ListenAndServe(addr, withH2C(mux)); err != nil {
+				log.Error("http server stopped", "addr", addr, "err", err)
+			}
+		}(net.JoinHostPort(host, p), mux)
+	}
+
 	ser, err := New(host, port)
 	if err != nil {
 		panic(err)
 	}
 
-	fmt.Println("server started")
+	log.Info("server started", "addr", net.JoinHostPort(host, port), "version", version)
 
 	defer ser.Stop()
 	ser.Run()
 }
+
+// httpMuxes returns optional http endpoints by port, health, metrics and h2c
+// multiply share one listener when their ports are equal.
+func httpMuxes() map[string]*http.ServeMux {
+	muxes := make(map[string]*http.ServeMux)
+	get := func(port string) *http.ServeMux {
+		if _, ok := muxes[port]; !ok {
+			muxes[port] = http.NewServeMux()
+		}
+		return muxes[port]
+	}
+
+	if healthport != "" {
+		get(healthport).HandleFunc("/healthz", healthHandler)
+	}
+
+	if metricsport != "" {
+		get(metricsport).Handle("/metrics", promhttp.Handler())
+	}
+
+	if h2cport != "" {
+		get(h2cport).HandleFunc(mulPath, mulHandler)
+	}
+
+	return muxes
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "service2"
+
+// Message types.
+const (
+	msgMul  = "mul"
+	msgPing = "ping"
+)
+
+var (
+	connsAccepted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "connections_accepted_total",
+		Help:      "Number of accepted connections.",
+	})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "connections_active",
+		Help:      "Number of connections being served.",
+	}, func() float64 { return float64(proc.health().Load) })
+
+	msgsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_processed_total",
+		Help:      "Number of answered messages by type.",
+	}, []string{"type"})
+
+	pairsMultiplied = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pairs_multiplied_total",
+		Help:      "Number of multiplied pairs.",
+	})
+
+	parseErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parse_errors_total",
+		Help:      "Number of messages which cant be parsed by kind, format, syntax or range.",
+	}, []string{"kind"})
+
+	msgDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "message_duration_seconds",
+		Help:      "Latency of processing message from read to written answer by type.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+	}, []string{"type"})
+
+	bytesIn = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bytes_in_total",
+		Help:      "Number of read message bytes.",
+	})
+
+	bytesOut = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bytes_out_total",
+		Help:      "Number of written answer bytes.",
+	})
+)
+
+func parseErrKind(err error) string {
+	switch {
+	case errors.Is(err, strconv.ErrSyntax):
+		return "syntax"
+	case errors.Is(err, strconv.ErrRange):
+		return "range"
+	}
+
+	return "format"
+}
@@ -2,12 +2,20 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
+	"service1/tracing"
 	"strconv"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Server .
@@ -35,10 +43,11 @@ func (s *Server) Run() error {
 	for {
 		conn, err := s.listener.Accept()
 		if err != nil {
-			fmt.Println(err)
+			slog.Error("cant accept connection", "err", err)
 			continue
 		}
-		fmt.Println("new conn")
+		slog.Debug("new conn", "remote_addr", conn.RemoteAddr().String())
+		connsAccepted.Inc()
 		conn.SetReadDeadline(time.Now().Add(1 * time.Minute))
 
 		errCh := handleConn(conn)
@@ -48,7 +57,9 @@ func (s *Server) Run() error {
 
 func handleErr(ch chan error) {
 	go func() {
-		fmt.Println(<-ch)
+		if err := <-ch; err != nil {
+			slog.Warn("cant handle connection", "request_id", requestID(err), "err", err)
+		}
 	}()
 }
 
@@ -58,6 +69,7 @@ func handleConn(conn io.ReadWriteCloser) chan error {
 	go func() {
 		defer close(errch)
 		defer conn.Close()
+		defer proc.track()()
 
 		buf := bufio.NewReader(conn)
 		bs, err := buf.ReadBytes(msgdelim)
@@ -68,23 +80,71 @@ func handleConn(conn io.ReadWriteCloser) chan error {
 			return
 		}
 
-		pairs, err := unmarshalMsg(string(bs))
+		start := time.Now()
+		bytesIn.Add(float64(len(bs)))
+
+		hdr, rawhdr, msg := splitHeader(string(bs))
+		id := hdr[requestIDHeader]
+
+		// continue trace of the caller sent in traceparent header line
+		ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(hdr))
+		ctx, span := tracer().Start(ctx, "handle message", trace.WithSpanKind(trace.SpanKindServer))
+		defer func() { tracing.End(span, err) }()
+
+		if msg == pingMsg {
+			span.SetAttributes(attribute.String("message.type", msgPing))
+			if err = write(conn, rawhdr+marshalPong(proc.health()), msgPing, start); err != nil {
+				errch <- withRequestID(id, err)
+			}
+			return
+		}
+
+		span.SetAttributes(attribute.String("message.type", msgMul))
+
+		_, parse := tracer().Start(ctx, "parse")
+		pairs, err := unmarshalMsg(msg)
+		tracing.End(parse, err)
+
 		if err != nil {
-			errch <- err
+			parseErrors.WithLabelValues(parseErrKind(err)).Inc()
+			errch <- withRequestID(id, err)
 			return
 		}
 
+		_, compute := tracer().Start(ctx, "compute", trace.WithAttributes(attribute.Int("pairs", len(pairs))))
 		muls := mulPairs(pairs)
+		pairsMultiplied.Add(float64(len(muls)))
 		out := marshalMsg(muls)
+		compute.End()
 
-		if _, err = conn.Write([]byte(out)); err != nil {
-			errch <- err
+		_, wspan := tracer().Start(ctx, "write")
+		err = write(conn, rawhdr+out, msgMul, start)
+		tracing.End(wspan, err)
+
+		if err != nil {
+			errch <- withRequestID(id, err)
+			return
 		}
+
+		slog.Debug("message processed", "request_id", id, "pairs", len(pairs))
 	}()
 
 	return errch
 }
 
+// write sends answer to message of type read at start.
+func write(conn io.Writer, out, typ string, start time.Time) error {
+	n, err := conn.Write([]byte(out))
+	bytesOut.Add(float64(n))
+	if err != nil {
+		return err
+	}
+
+	msgsProcessed.WithLabelValues(typ).Inc()
+	msgDuration.WithLabelValues(typ).Observe(time.Since(start).Seconds())
+	return nil
+}
+
 type pair struct {
 	a, b int
 }
@@ -2,13 +2,22 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestHandleConn_OK(t *testing.T) {
@@ -27,6 +36,22 @@ func TestHandleConn_OK(t *testing.T) {
 	assert.Equal(t, buf.String(), res)
 }
 
+func TestHandleConn_Ping(t *testing.T) {
+	a, b := net.Pipe()
+	handleConn(b)
+
+	buf := bytes.NewBuffer([]byte(pingMsg))
+
+	_, err := buf.WriteTo(a)
+	assert.NoError(t, err)
+
+	_, err = buf.ReadFrom(a)
+	assert.NoError(t, err)
+
+	// load counts connections of other tests too
+	assert.Regexp(t, `^PONG\r\nversion=dev\r\nuptime=\d+\r\nload=[1-9]\d*\r\n\r\n $`, buf.String())
+}
+
 func TestHandleConn_UnmarshalErr(t *testing.T) {
 	req := "12,43\r\noops,3\r\n\r\n "
 	a, b := net.Pipe()
@@ -39,7 +64,7 @@ func TestHandleConn_UnmarshalErr(t *testing.T) {
 
 	err = <-errch
 
-	assert.ErrorAs(t, err, &strconv.ErrSyntax)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
 
 }
 
@@ -112,7 +137,7 @@ func TestUnmarshalMsg(t *testing.T) {
 
 			res, err := unmarshalMsg(tc.req)
 
-			if err != tc.err && !errors.As(err, &tc.err) {
+			if err != tc.err && !errors.Is(err, tc.err) {
 				t.Errorf("expecting %v %T, got %v, %T", tc.err, tc.err, err, err)
 			}
 
@@ -120,3 +145,167 @@ func TestUnmarshalMsg(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+
+	healthHandler(rec, req)
+
+	var h health
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &h))
+	assert.Equal(t, "dev", h.Version)
+	assert.GreaterOrEqual(t, h.Load, int64(0))
+}
+
+func TestHandleConn_Metrics(t *testing.T) {
+	req := "12,43\r\n11,3\r\n\r\n "
+	pairs := testutil.ToFloat64(pairsMultiplied)
+	msgs := testutil.ToFloat64(msgsProcessed.WithLabelValues(msgMul))
+	syntax := testutil.ToFloat64(parseErrors.WithLabelValues("syntax"))
+
+	a, b := net.Pipe()
+	handleConn(b)
+
+	buf := bytes.NewBuffer([]byte(req))
+	_, err := buf.WriteTo(a)
+	assert.NoError(t, err)
+
+	_, err = buf.ReadFrom(a)
+	assert.NoError(t, err)
+
+	a, b = net.Pipe()
+	errch := handleConn(b)
+
+	buf = bytes.NewBuffer([]byte("oops,3\r\n\r\n "))
+	_, err = buf.WriteTo(a)
+	assert.NoError(t, err)
+	<-errch
+
+	assert.Equal(t, pairs+2, testutil.ToFloat64(pairsMultiplied))
+	assert.Equal(t, msgs+1, testutil.ToFloat64(msgsProcessed.WithLabelValues(msgMul)))
+	assert.Equal(t, syntax+1, testutil.ToFloat64(parseErrors.WithLabelValues("syntax")))
+}
+
+func TestHTTPMuxes(t *testing.T) {
+	defer func(h, m string) { healthport, metricsport = h, m }(healthport, metricsport)
+
+	healthport, metricsport = "", ""
+	assert.Empty(t, httpMuxes())
+
+	healthport, metricsport = "9001", "9001"
+	assert.Len(t, httpMuxes(), 1)
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	httpMuxes()["9001"].ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "service2_connections_active")
+
+	healthport, metricsport = "9001", "9002"
+	assert.Len(t, httpMuxes(), 2)
+
+	defer func(p string) { h2cport = p }(h2cport)
+
+	h2cport = "9001"
+	assert.Len(t, httpMuxes(), 2)
+
+	rec = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, mulPath, bytes.NewBufferString("2,3\r\n\r\n "))
+	httpMuxes()["9001"].ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "6\r\n\r\n ", rec.Body.String())
+}
+
+func TestHandleConn_RequestID(t *testing.T) {
+	req, res := "@request-id=abc\r\n12,43\r\n\r\n ", "@request-id=abc\r\n516\r\n\r\n "
+	a, b := net.Pipe()
+	handleConn(b)
+
+	buf := bytes.NewBuffer([]byte(req))
+
+	_, err := buf.WriteTo(a)
+	assert.NoError(t, err)
+
+	_, err = buf.ReadFrom(a)
+	assert.NoError(t, err)
+
+	assert.Equal(t, res, buf.String())
+
+	a, b = net.Pipe()
+	errch := handleConn(b)
+
+	buf = bytes.NewBuffer([]byte("@request-id=abc\r\noops,3\r\n\r\n "))
+	_, err = buf.WriteTo(a)
+	assert.NoError(t, err)
+
+	err = <-errch
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	assert.Equal(t, "abc", requestID(err))
+}
+
+func TestSplitHeader(t *testing.T) {
+	testCases := []struct {
+		name   string
+		str    string
+		hdr    map[string]string
+		rawhdr string
+		msg    string
+	}{
+		{
+			name: "no header",
+			str:  "12,43\r\n\r\n ",
+			hdr:  map[string]string{},
+			msg:  "12,43\r\n\r\n ",
+		},
+		{
+			name:   "headers",
+			str:    "@request-id=abc\r\n@traceparent=00-1-2-01\r\n12,43\r\n\r\n ",
+			hdr:    map[string]string{"request-id": "abc", "traceparent": "00-1-2-01"},
+			rawhdr: "@request-id=abc\r\n@traceparent=00-1-2-01\r\n",
+			msg:    "12,43\r\n\r\n ",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hdr, rawhdr, msg := splitHeader(tc.str)
+			assert.Equal(t, tc.hdr, hdr)
+			assert.Equal(t, tc.rawhdr, rawhdr)
+			assert.Equal(t, tc.msg, msg)
+		})
+	}
+}
+
+func TestHandleConn_Tracing(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	req := "@traceparent=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01\r\n12,43\r\n\r\n "
+	a, b := net.Pipe()
+	errch := handleConn(b)
+
+	buf := bytes.NewBuffer([]byte(req))
+	_, err := buf.WriteTo(a)
+	assert.NoError(t, err)
+
+	_, err = buf.ReadFrom(a)
+	assert.NoError(t, err)
+	<-errch
+
+	spans := exp.GetSpans()
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.SpanContext.TraceID().String())
+	}
+
+	assert.Equal(t, []string{"parse", "compute", "write", "handle message"}, names)
+	assert.Equal(t, "00f067aa0ba902b7", spans[3].Parent.SpanID().String())
+	assert.Equal(t, spans[3].SpanContext.SpanID(), spans[0].Parent.SpanID())
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer returns tracer of global provider set up by tracing.Init of
+// service1, the setup is shared by both services.
+func tracer() trace.Tracer {
+	return otel.Tracer("service2")
+}